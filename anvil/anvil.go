@@ -7,10 +7,15 @@ import (
 	"mutably/anvil/model"
 	"mutably/anvil/model/inflection"
 	"mutably/anvil/parser"
+	"mutably/anvil/parser/revision"
 	"mutably/anvil/parser/verb"
 	"mutably/anvil/view"
 	"os"
+	"os/signal"
+	"path/filepath"
 	"runtime"
+	"syscall"
+	"time"
 )
 
 // Run makes the application perform the requested operation.
@@ -35,6 +40,10 @@ func init() {
 		"The hostname of the database")
 	flag.UintVar(&flags.DBPort, "port", 5432,
 		"The database port")
+	flag.BoolVar(&flags.Resume, "resume", false,
+		"Resume an import from its last checkpoint")
+	flag.IntVar(&flags.BatchSize, "batch", 5000,
+		"Write rows to the database in batches of N (0 disables batching)")
 
 	if len(os.Args) == 1 {
 		Run = ShowHelp
@@ -54,7 +63,11 @@ func ShowHelp() {
 
 Commands:
 * import
-    - Imports an XML archive
+    - Imports an XML archive (which may be compressed with bzip2 or gzip)
+* update
+    - Applies incremental (adds-changes) XML archives to an earlier import
+* dedupe
+    - Removes duplicate verb forms left by repeated imports
 * view
     - Views a specific page of an XML archive
 * help
@@ -62,18 +75,187 @@ Commands:
 	`)
 }
 
-// Import processes the contents of an archive.
+// Import processes the contents of an archive. The revision of each page is
+// recorded so that later updates can tell which pages changed.
 func Import(args *AppFlags) {
 	if flag.NArg() != 1 || args.MissingDBCredentials() {
-		fmt.Println("Usage: anvil import -d [-h] [-port] -u -p <file>")
+		fmt.Println("Usage: anvil import -d [-h] [-port] -u -p [-resume] [-batch] <file>")
 		os.Exit(1)
 	}
 
-	archive, err := os.Open(flag.Arg(0))
+	archive, err := parser.OpenArchive(flag.Arg(0))
 	if err != nil {
 		log.Fatal(err)
 	}
+	defer archive.Close()
 
+	db := connect(args)
+	vparser, err := verb.NewVerbParser(db, runtime.GOMAXPROCS(0),
+		args.PageLimit, newConjugators())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	archiveName := filepath.Base(flag.Arg(0))
+	var checkpoint *model.Checkpoint
+	if args.Resume {
+		checkpoint, err = db.GetCheckpoint(archiveName)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	// Let Ctrl-C finish the pages in the job queue rather than lose them.
+	onInterrupt(func() {
+		log.Println("Stopping import after queued pages are processed")
+		vparser.Stop()
+	})
+
+	done := make(chan struct{})
+	go saveCheckpoints(db, archiveName, vparser, done)
+
+	if checkpoint != nil {
+		log.Printf("Resuming %s after page '%s'\n", archiveName,
+			checkpoint.PageTitle)
+		err = parser.ResumePages(archive, vparser, checkpoint.Offset)
+	} else {
+		err = parser.ProcessPages(archive, vparser)
+	}
+	if err != nil {
+		log.Println(err)
+	}
+	vparser.Wait()
+
+	// A failed flush lost rows of pages before the last checkpoint, so the
+	// import stops without saving one.
+	close(done)
+	if err := saveCheckpoint(db, archiveName, vparser); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// Update applies incremental (adds-changes) archives to an earlier import.
+// Only pages that have new revisions are processed; verb forms that were
+// removed from those pages are deleted.
+//
+// Archives are applied in the order given, which should be oldest first.
+func Update(args *AppFlags) {
+	if flag.NArg() == 0 || args.MissingDBCredentials() {
+		fmt.Println("Usage: anvil update -d [-h] [-port] -u -p [-batch] <file>...")
+		os.Exit(1)
+	}
+
+	db := connect(args)
+	conjugators := newConjugators()
+
+	// Like an import, an interrupted update finishes the queued pages and
+	// records them before it stops. Later archives are not applied.
+	interrupted := make(chan struct{})
+	onInterrupt(func() {
+		log.Println("Stopping update after queued pages are processed")
+		close(interrupted)
+	})
+
+	for _, path := range flag.Args() {
+		err := update(path, db, conjugators, args, interrupted)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		select {
+		case <-interrupted:
+			return
+		default:
+		}
+	}
+}
+
+// update applies the incremental archive at path. It stops early once
+// interrupted is closed.
+func update(path string, db store,
+	conjugators map[string]inflection.Conjugator, args *AppFlags,
+	interrupted <-chan struct{}) error {
+	archive, err := parser.OpenArchive(path)
+	if err != nil {
+		return err
+	}
+	defer archive.Close()
+
+	// Each archive gets its own parser so that a page which changed in
+	// more than one of them is fully processed before it is replaced.
+	vparser, err := verb.NewVerbParser(db, runtime.GOMAXPROCS(0),
+		args.PageLimit, conjugators)
+	if err != nil {
+		return err
+	}
+
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-interrupted:
+			vparser.Stop()
+		case <-done:
+		}
+	}()
+
+	filter := revision.NewFilter(db, vparser)
+	err = parser.ProcessPages(archive, filter)
+	vparser.Wait()
+	close(done)
+
+	// The next archive may delete content from this one, which it can only
+	// do once that content is written. Revisions are recorded after that,
+	// so pages whose content was lost are processed again by a later run.
+	if saveErr := saveRevisions(db, vparser); saveErr != nil && err == nil {
+		err = saveErr
+	}
+
+	log.Printf("Updated %d pages from %s\n", filter.PagesChanged,
+		filepath.Base(path))
+	return err
+}
+
+// Dedupe removes copies of verb forms that were stored by earlier imports
+// and adds the unique index that prevents new ones.
+func Dedupe(args *AppFlags) {
+	if args.MissingDBCredentials() {
+		fmt.Println("Usage: anvil dedupe -d [-h] [-port] -u -p")
+		os.Exit(1)
+	}
+
+	removed, err := openPsqlDB(args).RemoveDuplicates()
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("Removed %d duplicate verb forms\n", removed)
+}
+
+// store is a database that imports and updates write to.
+type store interface {
+	model.Database
+	model.CheckpointStore
+	model.RevisionStore
+	// Flush writes any rows that are buffered.
+	Flush() error
+}
+
+// connect opens the database described by args. Writes are batched unless
+// args.BatchSize is zero.
+func connect(args *AppFlags) store {
+	psqlDB := openPsqlDB(args)
+	if args.BatchSize == 0 {
+		return psqlDB
+	}
+
+	batchDB, err := model.NewBatchDB(psqlDB, args.BatchSize)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return batchDB
+}
+
+// openPsqlDB opens the database described by args.
+func openPsqlDB(args *AppFlags) *model.PsqlDB {
 	psqlDB, err := model.NewPsqlDB(model.KeyRing{
 		DatabaseName: args.DBName,
 		Host:         args.DBHost,
@@ -84,20 +266,94 @@ func Import(args *AppFlags) {
 	if err != nil {
 		log.Fatal(err)
 	}
+	return psqlDB
+}
 
+// newConjugators creates a conjugator for each supported language, keyed
+// by the language's canonical name.
+func newConjugators() map[string]inflection.Conjugator {
 	dutch := inflection.NewDutch()
-	conjugators := map[string]inflection.Conjugator{
-		dutch.GetLanguage().String(): dutch,
+	german := inflection.NewGerman()
+	return map[string]inflection.Conjugator{
+		dutch.GetLanguage().String():  dutch,
+		german.GetLanguage().String(): german,
 	}
+}
 
-	vparser, err := verb.NewVerbParser(psqlDB, runtime.GOMAXPROCS(0),
-		args.PageLimit, conjugators)
-	if err != nil {
-		log.Fatal(err)
+// onInterrupt calls stop the first time the process is interrupted or
+// terminated. Signals are then handled as usual again, so a second Ctrl-C
+// aborts a drain that takes too long.
+func onInterrupt(stop func()) {
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-interrupts
+		signal.Stop(interrupts)
+		stop()
+	}()
+}
+
+// saveCheckpoints periodically records the progress of vparser through an
+// archive until done is closed. vparser is stopped if a checkpoint can't be
+// saved, since the rows it would have covered may not have been written.
+func saveCheckpoints(db store, archiveName string,
+	vparser *verb.VerbParser, done <-chan struct{}) {
+	const checkpointInterval = time.Minute
+	ticker := time.NewTicker(checkpointInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := saveCheckpoint(db, archiveName, vparser); err != nil {
+				log.Println("Stopping import;", err)
+				vparser.Stop()
+				return
+			}
+		case <-done:
+			return
+		}
 	}
+}
 
-	parser.ProcessPages(archive, vparser)
-	vparser.Wait()
+// saveCheckpoint writes buffered rows, records the revisions of the pages
+// they came from, and then records the last page that vparser has finished.
+func saveCheckpoint(db store, archiveName string,
+	vparser *verb.VerbParser) error {
+	page, ok := vparser.LastPage()
+	if err := saveRevisions(db, vparser); err != nil {
+		return err
+	}
+	if !ok {
+		return nil
+	}
+
+	return db.SaveCheckpoint(&model.Checkpoint{
+		Archive:   archiveName,
+		PageId:    page.Id,
+		PageTitle: page.Title,
+		Offset:    page.EndOffset,
+	})
+}
+
+// saveRevisions writes the rows that db has buffered and then records the
+// revisions of the pages that vparser processed before that.
+func saveRevisions(db store, vparser *verb.VerbParser) error {
+	pages := vparser.TakeProcessed()
+	if err := db.Flush(); err != nil {
+		return err
+	}
+
+	revisions := make([]*model.PageRevision, len(pages))
+	for i, page := range pages {
+		revisions[i] = &model.PageRevision{
+			PageId:     page.Id,
+			Title:      page.Title,
+			RevisionId: page.Revision.Id,
+			Timestamp:  page.Revision.Timestamp,
+		}
+	}
+	return db.SavePageRevisions(revisions)
 }
 
 // View displays content from the archive.
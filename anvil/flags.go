@@ -18,7 +18,7 @@ type AppFlags struct {
 
 	// ----------------------------
 
-	// Flags specific to importing
+	// Flags specific to importing and updating
 
 	// The name of a database
 	DBName string
@@ -34,6 +34,13 @@ type AppFlags struct {
 
 	// The port of DBName's server
 	DBPort uint
+
+	// Continue an import from the last checkpoint for its archive
+	Resume bool
+
+	// The number of rows to buffer before writing them to the database.
+	// Zero writes each row immediately.
+	BatchSize int
 }
 
 // GetIntent uses command-line flags to decide what the user wants this
@@ -54,6 +61,12 @@ func (flags *AppFlags) GetIntent() func() {
 	case "import":
 		return func() { Import(flags) }
 
+	case "update":
+		return func() { Update(flags) }
+
+	case "dedupe":
+		return func() { Dedupe(flags) }
+
 	case "view":
 		return View
 
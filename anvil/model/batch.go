@@ -0,0 +1,529 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/lib/pq"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// BatchDB is a Database that buffers writes and sends them to PostgreSQL in
+// batches. Each batch is written in one transaction: words and verb forms
+// with COPY and infinitives with multi-row inserts. Verb forms that are
+// already stored or that admins deleted are skipped, as they are by PsqlDB.
+//
+// Callers need word ids before the words are written, so BatchDB keeps the id
+// of every word in memory and reserves ids for new words from the words
+// sequence. Another process that added, renamed, or deleted a word would make
+// those ids wrong, so BatchDB holds an advisory lock until it is released.
+// The API's word edits take the same lock and fail while it is held; other
+// imports wait for it.
+//
+// Buffered writes are not visible to queries (or deletions) until they are
+// flushed. If a batch fails, its words are kept for the next one, since
+// callers already hold their ids, but its verb forms and infinitives are
+// lost. Every later Flush then returns the error so that the import can be
+// stopped and run again.
+type BatchDB struct {
+	*PsqlDB
+	// The number of buffered rows that triggers a flush
+	batchSize int
+
+	// Guards the fields below it
+	mutex sync.Mutex
+	// The id of every known word
+	wordIds map[wordKey]int
+	// Ids reserved from the words sequence that have not been used
+	freeIds []int
+	// Rows that have not been flushed
+	words       [][]interface{}
+	verbForms   [][]interface{}
+	infinitives map[infinitiveKey][]interface{}
+	// The first error that cost buffered rows
+	err error
+
+	// Flushes hold this while they write so that batches reach the database
+	// in the order they were filled. A verb form never reaches it before
+	// the word it refers to.
+	flushMutex sync.Mutex
+
+	// The connection that holds the words lock
+	lock *sql.Conn
+
+	// Ids of the rows in lookup tables, keyed by name
+	tenseIds map[string]int
+	moodIds  map[string]int
+	classIds map[string]int
+}
+
+// wordKey is the natural key of the words table.
+type wordKey struct {
+	languageId int
+	word       string
+}
+
+// infinitiveKey is the primary key of the infinitives table.
+type infinitiveKey struct {
+	languageId int
+	wordId     int
+}
+
+// wordsLock is the advisory lock that keeps editors from changing words
+// while a BatchDB is in use. The key must match the API's.
+const wordsLock = 0x776f726473
+
+// NewBatchDB creates a *BatchDB that writes to db in batches of batchSize
+// rows. It waits for the words lock, which is held until Release is called,
+// and then loads existing words into memory.
+func NewBatchDB(db *PsqlDB, batchSize int) (*BatchDB, error) {
+	if batchSize < 1 {
+		return nil, errors.New("batchSize must be at least 1")
+	}
+
+	// Session locks belong to a connection, so one is kept for the lock.
+	lock, err := db.Conn(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	_, err = lock.ExecContext(context.Background(),
+		`SELECT pg_advisory_lock($1)`, wordsLock)
+	if err != nil {
+		lock.Close()
+		return nil, err
+	}
+
+	batchDB := &BatchDB{
+		PsqlDB:      db,
+		batchSize:   batchSize,
+		infinitives: make(map[infinitiveKey][]interface{}),
+		lock:        lock,
+	}
+	if err = batchDB.load(); err != nil {
+		batchDB.Release()
+		return nil, err
+	}
+	return batchDB, nil
+}
+
+// load reads the ids of words and of the rows in lookup tables.
+func (db *BatchDB) load() error {
+	var err error
+	if db.wordIds, err = db.loadWordIds(); err != nil {
+		return err
+	}
+
+	lookups := []struct {
+		ids   *map[string]int
+		query string
+	}{
+		{&db.tenseIds, `SELECT tense, id FROM tenses`},
+		{&db.moodIds, `SELECT mood, id FROM moods`},
+		{&db.classIds, `SELECT class, id FROM verb_classes`},
+	}
+	for _, lookup := range lookups {
+		if *lookup.ids, err = db.loadIds(lookup.query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Release gives up the words lock so that editors can change words again.
+// Rows should be flushed first; db must not be used afterwards. The
+// underlying PsqlDB stays open.
+func (db *BatchDB) Release() error {
+	_, err := db.lock.ExecContext(context.Background(),
+		`SELECT pg_advisory_unlock($1)`, wordsLock)
+	if closeErr := db.lock.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// loadWordIds returns the id of every word.
+func (db *PsqlDB) loadWordIds() (map[wordKey]int, error) {
+	rows, err := db.Query(`SELECT lang_id, word, id FROM words`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make(map[wordKey]int)
+	for rows.Next() {
+		var key wordKey
+		var id int
+		if err := rows.Scan(&key.languageId, &key.word, &id); err != nil {
+			return nil, err
+		}
+		ids[key] = id
+	}
+	return ids, rows.Err()
+}
+
+// loadIds maps the first column of query's rows to the second.
+func (db *PsqlDB) loadIds(query string) (map[string]int, error) {
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make(map[string]int)
+	for rows.Next() {
+		var name string
+		var id int
+		if err := rows.Scan(&name, &id); err != nil {
+			return nil, err
+		}
+		ids[name] = id
+	}
+	return ids, rows.Err()
+}
+
+// InsertWord buffers word if it is new to its language. The id of the new
+// (or existing) word is returned. InsertWord has no way to report errors, so
+// they are returned by the next call to Flush.
+func (db *BatchDB) InsertWord(languageId int, word string) int {
+	key := wordKey{languageId, word}
+
+	db.mutex.Lock()
+	wordId, ok := db.wordIds[key]
+	if !ok {
+		var err error
+		if wordId, err = db.reserveWordId(); err != nil {
+			db.fail(err)
+			db.mutex.Unlock()
+			return 0
+		}
+		db.wordIds[key] = wordId
+		db.words = append(db.words, []interface{}{wordId, languageId, word})
+	}
+	isFull := db.bufferedRows() >= db.batchSize
+	db.mutex.Unlock()
+
+	if isFull {
+		db.Flush()
+	}
+	return wordId
+}
+
+// reserveWordId takes an unused id from the words sequence. The caller
+// must hold db.mutex.
+func (db *BatchDB) reserveWordId() (int, error) {
+	if len(db.freeIds) == 0 {
+		rows, err := db.Query(`
+			SELECT nextval(pg_get_serial_sequence('words', 'id'))
+			FROM generate_series(1, $1)`,
+			db.batchSize,
+		)
+		if err != nil {
+			return 0, err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var id int
+			if err := rows.Scan(&id); err != nil {
+				return 0, err
+			}
+			db.freeIds = append(db.freeIds, id)
+		}
+		if err := rows.Err(); err != nil {
+			return 0, err
+		}
+	}
+
+	id := db.freeIds[0]
+	db.freeIds = db.freeIds[1:]
+	return id, nil
+}
+
+// InsertVerbForm buffers a verb form.
+// verb should have all fields (except maybe Person) populated. Plural forms
+// only store a person if the language distinguishes them (e.g., German 'ihr').
+func (db *BatchDB) InsertVerbForm(verb *VerbForm) error {
+	var person, number, pageId interface{} = verb.Person, 1, nil
+	if verb.PageId != 0 {
+		pageId = verb.PageId
+	}
+	if verb.Number != Singular {
+		number = 2
+		if verb.Person == 0 {
+			person = nil
+		}
+	}
+
+	// A bad row would fail the whole batch, so catch what PsqlDB would
+	// reject before it is buffered.
+	if verb.LanguageId == 0 || verb.InfinitiveId == 0 {
+		return errors.New("Cannot insert verb form " + verb.Word +
+			" without a language and infinitive")
+	}
+
+	db.mutex.Lock()
+	wordId, ok := db.wordIds[wordKey{verb.LanguageId, verb.Word}]
+	tenseId, hasTense := db.tenseIds[tenseName(verb.Tense)]
+	moodId, hasMood := db.moodIds[moodName(verb.Mood)]
+	if !ok || !hasTense || !hasMood {
+		db.mutex.Unlock()
+		return errors.New("Cannot insert verb form " + verb.Word +
+			"; its word, tense, or mood is unknown")
+	}
+
+	db.verbForms = append(db.verbForms, []interface{}{verb.LanguageId, wordId,
+		verb.InfinitiveId, tenseId, moodId, person, number, pageId})
+	isFull := db.bufferedRows() >= db.batchSize
+	db.mutex.Unlock()
+
+	if isFull {
+		return db.Flush()
+	}
+	return nil
+}
+
+// InsertInfinitive buffers the properties of an infinitive verb.
+// If the infinitive already has properties, they are replaced.
+// Non-finite forms and the auxiliary should already exist as words.
+func (db *BatchDB) InsertInfinitive(infinitive *Infinitive) error {
+	// Missing values are stored as null, just as PsqlDB does.
+	classId := func(name string) interface{} {
+		if id, ok := db.classIds[name]; ok {
+			return id
+		}
+		return nil
+	}
+	wordId := func(word string) interface{} {
+		if id, ok := db.wordIds[wordKey{infinitive.LanguageId, word}]; ok {
+			return id
+		}
+		return nil
+	}
+	text := func(s string) interface{} {
+		if s == "" {
+			return nil
+		}
+		return s
+	}
+	id := func(id int) interface{} {
+		if id == 0 {
+			return nil
+		}
+		return id
+	}
+
+	if infinitive.LanguageId == 0 || infinitive.WordId == 0 {
+		return errors.New("Cannot insert an infinitive without a language " +
+			"and word")
+	}
+
+	db.mutex.Lock()
+	key := infinitiveKey{infinitive.LanguageId, infinitive.WordId}
+	db.infinitives[key] = []interface{}{
+		infinitive.LanguageId,
+		infinitive.WordId,
+		classId(className(infinitive.Class)),
+		text(infinitive.Subclass),
+		text(infinitive.Prefix),
+		wordId(infinitive.Auxiliary),
+		wordId(infinitive.PresentParticiple),
+		wordId(infinitive.PastParticiple),
+		wordId(infinitive.Gerund),
+		id(infinitive.PageId),
+	}
+	isFull := db.bufferedRows() >= db.batchSize
+	db.mutex.Unlock()
+
+	if isFull {
+		return db.Flush()
+	}
+	return nil
+}
+
+// bufferedRows returns the number of rows waiting to be flushed. The caller
+// must hold db.mutex.
+func (db *BatchDB) bufferedRows() int {
+	return len(db.words) + len(db.verbForms) + len(db.infinitives)
+}
+
+// fail records err unless an earlier error was recorded. The caller must
+// hold db.mutex.
+func (db *BatchDB) fail(err error) {
+	if db.err == nil {
+		db.err = err
+	}
+}
+
+// Flush writes all buffered rows to the database. It returns the error of
+// the first batch that failed, even if this one succeeded.
+func (db *BatchDB) Flush() error {
+	db.flushMutex.Lock()
+	defer db.flushMutex.Unlock()
+
+	db.mutex.Lock()
+	words, verbForms, infinitives := db.words, db.verbForms, db.infinitives
+	db.words, db.verbForms = nil, nil
+	db.infinitives = make(map[infinitiveKey][]interface{})
+	db.mutex.Unlock()
+
+	err := writeBatch(db.PsqlDB, words, verbForms, infinitives)
+
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+	if err != nil {
+		// Rows buffered since, and the caches of conjugators, refer to the
+		// ids of these words. Dropping them would fail every later batch.
+		db.words = append(words, db.words...)
+		db.fail(err)
+	}
+	return db.err
+}
+
+// writeBatch writes rows to db in one transaction.
+func writeBatch(db *PsqlDB, words, verbForms [][]interface{},
+	infinitives map[infinitiveKey][]interface{}) error {
+	if len(words) == 0 && len(verbForms) == 0 && len(infinitives) == 0 {
+		return nil
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+
+	err = copyRows(tx, "words", []string{"id", "lang_id", "word"}, words)
+	if err == nil {
+		err = insertVerbForms(tx, verbForms)
+	}
+	if err == nil {
+		err = insertInfinitives(tx, infinitives)
+	}
+
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// copyRows uses COPY to add rows to the columns of a table.
+func copyRows(tx *sql.Tx, table string, columns []string,
+	rows [][]interface{}) error {
+	if len(rows) == 0 {
+		return nil
+	}
+
+	stmt, err := tx.Prepare(pq.CopyIn(table, columns...))
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		if _, err = stmt.Exec(row...); err != nil {
+			stmt.Close()
+			return err
+		}
+	}
+	if _, err = stmt.Exec(); err != nil {
+		stmt.Close()
+		return err
+	}
+	return stmt.Close()
+}
+
+// insertVerbForms adds the rows that are not already in the verb_forms
+// table and that admins have not deleted. COPY stops at the first duplicate, so rows are copied to a staging
+// table and moved from there.
+func insertVerbForms(tx *sql.Tx, rows [][]interface{}) error {
+	if len(rows) == 0 {
+		return nil
+	}
+
+	_, err := tx.Exec(`
+		CREATE TEMP TABLE verb_forms_staging ON COMMIT DROP AS
+		SELECT lang_id, word_id, inf_id, tense_id, mood_id, person, num,
+		  page_id
+		FROM verb_forms
+		WITH NO DATA`)
+	if err != nil {
+		return err
+	}
+
+	err = copyRows(tx, "verb_forms_staging", []string{"lang_id", "word_id",
+		"inf_id", "tense_id", "mood_id", "person", "num", "page_id"}, rows)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO verb_forms
+		  (lang_id, word_id, inf_id, tense_id, mood_id, person, num, page_id)
+		SELECT lang_id, word_id, inf_id, tense_id, mood_id, person, num,
+		  page_id
+		FROM verb_forms_staging vf
+		WHERE ` + notDeleted + `
+		ON CONFLICT DO NOTHING`)
+	return err
+}
+
+// insertInfinitives upserts rows into the infinitives table using multi-row
+// inserts. COPY cannot be used because existing rows must be replaced.
+func insertInfinitives(tx *sql.Tx, rows map[infinitiveKey][]interface{}) error {
+	// Keep statements well below the limit of 65535 parameters.
+	const rowsPerStatement = 1000
+
+	var values []string
+	var args []interface{}
+	insert := func() error {
+		_, err := tx.Exec(`
+			INSERT INTO infinitives (`+infinitiveColumns+`)
+			VALUES `+strings.Join(values, ", ")+`
+			`+onInfinitiveConflict,
+			args...,
+		)
+		values, args = values[:0], args[:0]
+		return err
+	}
+
+	for _, row := range rows {
+		placeholders := make([]string, len(row))
+		for i := range row {
+			placeholders[i] = "$" + strconv.Itoa(len(args)+i+1)
+		}
+		values = append(values, "("+strings.Join(placeholders, ", ")+")")
+		args = append(args, row...)
+
+		if len(values) == rowsPerStatement {
+			if err := insert(); err != nil {
+				return err
+			}
+		}
+	}
+	if len(values) != 0 {
+		return insert()
+	}
+	return nil
+}
+
+// SaveCheckpoint flushes buffered rows and then stores checkpoint. Pages
+// before the checkpoint are therefore fully written. No checkpoint is saved
+// once a batch has failed.
+func (db *BatchDB) SaveCheckpoint(checkpoint *Checkpoint) error {
+	if err := db.Flush(); err != nil {
+		return err
+	}
+	return db.PsqlDB.SaveCheckpoint(checkpoint)
+}
+
+// SavePageRevisions stores revisions unless a batch has failed, since the
+// content of those pages may have been lost.
+func (db *BatchDB) SavePageRevisions(revisions []*PageRevision) error {
+	db.mutex.Lock()
+	err := db.err
+	db.mutex.Unlock()
+	if err != nil {
+		return err
+	}
+	return db.PsqlDB.SavePageRevisions(revisions)
+}
@@ -0,0 +1,175 @@
+package model_test
+
+import (
+	"mutably/anvil/model"
+	"os"
+	"strconv"
+	"testing"
+)
+
+// The tests and benchmarks write to the database named by the same
+// environment variables that the docker containers use. They are skipped if
+// those are not set. Rows are added under a language of their own and
+// removed after.
+
+func BenchmarkPsqlDB(b *testing.B) {
+	db := openTestDB(b)
+	defer db.Close()
+	benchmarkInserts(b, db, db)
+}
+
+func BenchmarkBatchDB(b *testing.B) {
+	db := openTestDB(b)
+	defer db.Close()
+
+	batchDB, err := model.NewBatchDB(db, 5000)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer batchDB.Release()
+	benchmarkInserts(b, batchDB, db)
+}
+
+// A failed batch should be reported by every later flush, and the words it
+// held should be written with the next batch so that later verb forms can
+// refer to them.
+func TestBatchDB_failedFlush(t *testing.T) {
+	db := openTestDB(t)
+	defer db.Close()
+	language := model.NewLanguage("failedflush")
+	if err := db.InsertLanguage(language); err != nil {
+		t.Fatal(err)
+	}
+	defer cleanUp(t, db, language)
+
+	batchDB, err := model.NewBatchDB(db, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer batchDB.Release()
+	verbForm := func(infinitiveId int, word string) *model.VerbForm {
+		return &model.VerbForm{
+			LanguageId:   language.Id,
+			InfinitiveId: infinitiveId,
+			Word:         word,
+			Tense:        model.Present,
+			Mood:         model.Indicative,
+			Number:       model.Singular,
+			Person:       model.First,
+		}
+	}
+
+	// No word has this id, so the verb form fails its foreign key.
+	infinitiveId := batchDB.InsertWord(language.Id, "maken")
+	batchDB.InsertWord(language.Id, "maak")
+	batchDB.InsertVerbForm(verbForm(infinitiveId+1000000, "maak"))
+	if batchDB.Flush() == nil {
+		t.Fatal("Expected the batch with a bad verb form to fail")
+	}
+
+	batchDB.InsertVerbForm(verbForm(infinitiveId, "maak"))
+	if batchDB.Flush() == nil {
+		t.Error("Expected later flushes to report the failed batch")
+	}
+
+	var count int
+	err = db.QueryRow(`
+		SELECT count(*)
+		FROM verb_forms
+		WHERE lang_id = $1 AND inf_id = $2`,
+		language.Id, infinitiveId,
+	).Scan(&count)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Error("Expected the later batch to be imported, found", count,
+			"verb forms")
+	}
+}
+
+// benchmarkInserts imports b.N verbs, each with an infinitive and four
+// finite forms, into target.
+func benchmarkInserts(b *testing.B, target importTarget, db *model.PsqlDB) {
+	language := model.NewLanguage("benchmark")
+	if err := db.InsertLanguage(language); err != nil {
+		b.Fatal(err)
+	}
+	defer cleanUp(b, db, language)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		prefix := "benchmark" + strconv.Itoa(i)
+		infinitiveId := target.InsertWord(language.Id, prefix+"en")
+		target.InsertInfinitive(&model.Infinitive{
+			LanguageId: language.Id,
+			WordId:     infinitiveId,
+			Class:      model.Weak,
+			Gerund:     prefix + "en",
+		})
+
+		for j, person := range []model.GrammaticalPerson{model.First,
+			model.Second, model.Third, 0} {
+			word := prefix + strconv.Itoa(j)
+			target.InsertWord(language.Id, word)
+
+			number := model.Singular
+			if person == 0 {
+				number = model.Plural
+			}
+			err := target.InsertVerbForm(&model.VerbForm{
+				LanguageId:   language.Id,
+				InfinitiveId: infinitiveId,
+				Word:         word,
+				Tense:        model.Present,
+				Mood:         model.Indicative,
+				Number:       number,
+				Person:       person,
+			})
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+	if err := target.Flush(); err != nil {
+		b.Fatal(err)
+	}
+	b.StopTimer()
+}
+
+func openTestDB(tb testing.TB) *model.PsqlDB {
+	tb.Helper()
+
+	key := model.KeyRing{
+		DatabaseName: os.Getenv("DATABASE_NAME"),
+		Host:         os.Getenv("DATABASE_HOST"),
+		Port:         5432,
+		User:         os.Getenv("DATABASE_USER"),
+		Password:     os.Getenv("DATABASE_PASSWORD"),
+	}
+	if key.DatabaseName == "" || key.Host == "" || key.User == "" {
+		tb.Skip("DATABASE_NAME, DATABASE_HOST, and DATABASE_USER must be set")
+	}
+
+	db, err := model.NewPsqlDB(key)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return db
+}
+
+// cleanUp removes the rows that a test added.
+func cleanUp(tb testing.TB, db *model.PsqlDB, language *model.Language) {
+	queries := []string{
+		`DELETE FROM deleted_verb_forms WHERE lang_id = $1`,
+		`DELETE FROM verb_forms WHERE lang_id = $1`,
+		`DELETE FROM infinitives WHERE lang_id = $1`,
+		`DELETE FROM words WHERE lang_id = $1`,
+		`DELETE FROM languages WHERE id = $1`,
+	}
+	for _, query := range queries {
+		if _, err := db.Exec(query, language.Id); err != nil {
+			tb.Error(err)
+		}
+	}
+}
@@ -19,6 +19,7 @@ func (language *Language) String() string {
 	return strings.ToLower(language.text)
 }
 
+// GrammaticalTense is a property of finite verbs.
 type GrammaticalTense int
 
 const (
@@ -26,6 +27,15 @@ const (
 	Past
 )
 
+// GrammaticalMood is a property of finite verbs.
+type GrammaticalMood int
+
+const (
+	Indicative GrammaticalMood = iota
+	Subjunctive
+	Imperative
+)
+
 // GrammaticalNumber is a property of finite verbs.
 type GrammaticalNumber int
 
@@ -50,6 +60,43 @@ type VerbForm struct {
 
 	Word   string
 	Tense  GrammaticalTense
+	Mood   GrammaticalMood
 	Number GrammaticalNumber
 	Person GrammaticalPerson
+
+	// The id of the archive page that the form was imported from
+	PageId int
+}
+
+// VerbClass describes the pattern a verb follows when it is conjugated.
+type VerbClass int
+
+const (
+	Weak VerbClass = iota + 1
+	Strong
+	Mixed
+	Irregular
+)
+
+// Infinitive defines the properties shared by every form of a verb.
+type Infinitive struct {
+	LanguageId int
+	WordId     int
+
+	Class VerbClass
+	// A finer grouping within Class (e.g., the ablaut series of a strong verb)
+	Subclass string
+	// A prefix that detaches from finite forms (e.g., 'an' in 'anfangen')
+	Prefix string
+
+	// The verb used to build perfect tenses (e.g., 'hebben' or 'zijn')
+	Auxiliary string
+
+	// Non-finite forms
+	PresentParticiple string
+	PastParticiple    string
+	Gerund            string
+
+	// The id of the archive page that the infinitive was imported from
+	PageId int
 }
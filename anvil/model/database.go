@@ -1,10 +1,13 @@
 package model
 
+import "time"
+
 // A Database handles queries to a collection of application data.
 type Database interface {
 	InsertLanguage(*Language) error
-	InsertWord(string) (wordId int)
+	InsertWord(languageId int, word string) (wordId int)
 	InsertVerbForm(*VerbForm) error
+	InsertInfinitive(*Infinitive) error
 }
 
 // KeyRing contains credentials for connecting to a database.
@@ -15,3 +18,44 @@ type KeyRing struct {
 	User         string
 	Password     string
 }
+
+// A CheckpointStore records how far imports have progressed so that they
+// can be resumed.
+type CheckpointStore interface {
+	// GetCheckpoint returns the last checkpoint saved for an archive or
+	// nil if there is none.
+	GetCheckpoint(archive string) (*Checkpoint, error)
+	SaveCheckpoint(*Checkpoint) error
+}
+
+// Checkpoint marks the last page of an archive that was fully imported.
+type Checkpoint struct {
+	// The base name of the archive file
+	Archive   string
+	PageId    int
+	PageTitle string
+	// The position in the decompressed archive that follows the page
+	Offset int64
+}
+
+// A RevisionStore tracks which revision of each page was imported so that
+// later updates only process pages that changed.
+type RevisionStore interface {
+	// GetPageRevision returns the last revision imported for a page or nil
+	// if the page has not been imported.
+	GetPageRevision(pageId int) (*PageRevision, error)
+	// SavePageRevisions records the revisions that were imported. It
+	// should only be called once the content of the pages is written.
+	SavePageRevisions([]*PageRevision) error
+	// DeletePageContent removes the verb forms and infinitive properties
+	// that were imported from a page.
+	DeletePageContent(pageId int) error
+}
+
+// PageRevision identifies a version of an archive page.
+type PageRevision struct {
+	PageId     int
+	Title      string
+	RevisionId int
+	Timestamp  time.Time
+}
@@ -11,6 +11,8 @@ type Conjugator interface {
 	GetLanguage() *model.Language
 	// SetDatabase should tell the Conjugator where to store results.
 	SetDatabase(model.Database) error
-	// Conjugate should build part (or all of) a conjugation table.
-	Conjugate(verb, template string) error
+	// Conjugate should build part (or all of) a conjugation table from a
+	// template on the archive page with pageId. What it stores should record
+	// the page so that it can be replaced when the page changes.
+	Conjugate(pageId int, verb, template string) error
 }
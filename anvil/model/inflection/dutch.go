@@ -5,6 +5,7 @@ import (
 	"log"
 	"mutably/anvil/model"
 	"regexp"
+	"strings"
 	"sync"
 )
 
@@ -57,7 +58,7 @@ func (dutch *Dutch) SetDatabase(db model.Database) error {
 // Conjugate uses a verb's template to construct parts of the conjugation
 // table that it belongs to. Finite verbs should exist as a word in the
 // database before being passed to this method.
-func (dutch *Dutch) Conjugate(verb, template string) error {
+func (dutch *Dutch) Conjugate(pageId int, verb, template string) error {
 	// This header isn't useful for Dutch, but in the future it may be for
 	// other languages. Ignore it here rather than in the method that called
 	// this one.
@@ -65,31 +66,105 @@ func (dutch *Dutch) Conjugate(verb, template string) error {
 		return nil
 	}
 
-	if template == "{{nl-verb}}" {
-		dutch.handleInfinitive(verb)
+	if template == "{{nl-verb}}" || strings.HasPrefix(template, "{{nl-verb|") {
+		if err := dutch.handleHeadword(pageId, verb, template); err != nil {
+			return err
+		}
 		// The first tense plural should just be the infinitive.
-		return dutch.handleFinite(verb, "{{nl-verb form of|n=pl|t=pres|m=ind|"+
-			verb+"}}")
+		return dutch.handleFinite(pageId, verb,
+			"{{nl-verb form of|n=pl|t=pres|m=ind|"+verb+"}}")
 	} else {
-		return dutch.handleFinite(verb, template)
+		return dutch.handleFinite(pageId, verb, template)
 	}
 }
 
 // handleInfinitive manages an infinitive verb.
 func (dutch *Dutch) handleInfinitive(verb string) {
-	infinitiveId := dutch.database.InsertWord(verb)
+	infinitiveId := dutch.database.InsertWord(dutch.GetLanguage().Id, verb)
 
 	dutch.idCache.Lock()
 	dutch.idCache.m[verb] = infinitiveId
 	dutch.idCache.Unlock()
 }
 
+// handleHeadword stores an infinitive along with the non-finite forms and
+// auxiliary verb that its headword template defines.
+//
+// The template takes the form
+//   {{nl-verb|past singular|past participle|pres_ptc=...|aux=...}}
+// where every argument is optional. Verbs that can use either auxiliary list
+// both (e.g., aux=hebben/zijn); the first one is kept. The verb class is
+// read from the past forms.
+func (dutch *Dutch) handleHeadword(pageId int, verb, template string) error {
+	dutch.handleInfinitive(verb)
+
+	tmpl, ok := parseTemplate(template)
+	if !ok {
+		return errors.New("Invalid template for verb " + verb)
+	}
+
+	infinitive := &model.Infinitive{
+		LanguageId:        dutch.GetLanguage().Id,
+		WordId:            dutch.idCache.get(verb),
+		Class:             getDutchClass(tmpl.arg(0), tmpl.arg(1)),
+		PastParticiple:    tmpl.arg(1),
+		PresentParticiple: tmpl.params["pres_ptc"],
+		// Dutch uses the infinitive as a gerund (e.g., 'het krijgen').
+		Gerund: verb,
+		PageId: pageId,
+	}
+	if aux := strings.FieldsFunc(tmpl.params["aux"], func(r rune) bool {
+		return r == '/' || r == ','
+	}); len(aux) != 0 {
+		infinitive.Auxiliary = strings.TrimSpace(aux[0])
+	}
+
+	for _, word := range []string{infinitive.PastParticiple,
+		infinitive.PresentParticiple, infinitive.Auxiliary} {
+		if word != "" {
+			dutch.database.InsertWord(dutch.GetLanguage().Id, word)
+		}
+	}
+	return dutch.database.InsertInfinitive(infinitive)
+}
+
+// getDutchClass returns the class of a verb with the given past singular
+// and past participle, or 0 if either is unknown. Weak verbs end their past
+// singular in -de or -te and their participle in -d or -t (e.g., werkte,
+// gewerkt), while strong verbs change their vowel and end the participle in
+// -en (e.g., kreeg, gekregen). Mixed verbs have a weak past and a strong
+// participle (e.g., lachte, gelachen). Anything else is irregular (e.g.,
+// bracht, gebracht).
+func getDutchClass(pastSingular, pastParticiple string) model.VerbClass {
+	if pastSingular == "" || pastParticiple == "" {
+		return 0
+	}
+
+	weakPast := strings.HasSuffix(pastSingular, "de") ||
+		strings.HasSuffix(pastSingular, "te")
+	weakParticiple := strings.HasSuffix(pastParticiple, "d") ||
+		strings.HasSuffix(pastParticiple, "t")
+	strongParticiple := strings.HasSuffix(pastParticiple, "en")
+
+	switch {
+	case weakPast && weakParticiple:
+		return model.Weak
+	case weakPast && strongParticiple:
+		return model.Mixed
+	case !weakPast && strongParticiple:
+		return model.Strong
+	default:
+		return model.Irregular
+	}
+}
+
 // handleFinite manages a finite verb form.
-func (dutch *Dutch) handleFinite(verb, template string) error {
-	if !dutch.hasSupportedMood(template) {
-		return errors.New("Unsupported mood")
+func (dutch *Dutch) handleFinite(pageId int, verb, template string) error {
+	mood, err := dutch.getMood(template)
+	if err != nil {
+		return err
 	}
-	dutch.database.InsertWord(verb)
+	dutch.database.InsertWord(dutch.GetLanguage().Id, verb)
 
 	infinitive := dutch.infRef.FindStringSubmatch(template)
 	if infinitive == nil {
@@ -111,19 +186,25 @@ func (dutch *Dutch) handleFinite(verb, template string) error {
 		dutch.idCache.RUnlock()
 	}
 
-	verbForm, e := dutch.assemble(verb, template, infinitiveId)
+	verbForm, e := dutch.assemble(verb, template, mood, infinitiveId)
 	if e != nil {
 		return e
 	}
+	verbForm.PageId = pageId
 	return dutch.database.InsertVerbForm(verbForm)
 }
 
 // assemble uses a template to assemble the parts of a VerbForm.
-func (dutch *Dutch) assemble(verb, template string,
+func (dutch *Dutch) assemble(verb, template string, mood model.GrammaticalMood,
 	infinitiveId int) (*model.VerbForm, error) {
-	tense, err := dutch.getTense(template)
-	if err != nil {
-		return nil, err
+	// Imperatives only exist in the present tense, so templates omit it.
+	tense := model.Present
+	if mood != model.Imperative {
+		var err error
+		tense, err = dutch.getTense(template)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	numberMatch := dutch.number.FindStringSubmatch(template)
@@ -137,7 +218,10 @@ func (dutch *Dutch) assemble(verb, template string,
 	}
 
 	var person model.GrammaticalPerson
-	if number == model.Singular {
+	if mood == model.Imperative {
+		// Commands are always given to the second person.
+		person = model.Second
+	} else if number == model.Singular {
 		person = dutch.getPerson(template)
 	}
 
@@ -146,6 +230,7 @@ func (dutch *Dutch) assemble(verb, template string,
 		InfinitiveId: infinitiveId,
 		Word:         verb,
 		Tense:        tense,
+		Mood:         mood,
 		Number:       number,
 		Person:       person,
 	}, nil
@@ -207,13 +292,23 @@ func (dutch *Dutch) getPerson(template string) model.GrammaticalPerson {
 	return person
 }
 
-// hasSupportedMood returns true if the verb having a certain mood can be
-// conjugated, false otherwise.
-func (dutch *Dutch) hasSupportedMood(template string) bool {
+// getMood extracts the grammatical mood from a template.
+func (dutch *Dutch) getMood(template string) (model.GrammaticalMood, error) {
 	moods := dutch.mood.FindStringSubmatch(template)
-	// When other moods are added, you can just group them separate from the
-	// null check.
-	return moods != nil && moods[1] == "ind"
+	if moods == nil {
+		return 0, errors.New("Unsupported mood")
+	}
+
+	switch moods[1] {
+	case "ind":
+		return model.Indicative, nil
+	case "sub":
+		return model.Subjunctive, nil
+	case "imp":
+		return model.Imperative, nil
+	default:
+		return 0, errors.New("Unsupported mood")
+	}
 }
 
 // Cache for infinitive word ids
@@ -221,3 +316,10 @@ type cache struct {
 	m map[string]int
 	sync.RWMutex
 }
+
+// get returns the id of an infinitive or 0 if it is not cached.
+func (c *cache) get(infinitive string) int {
+	c.RLock()
+	defer c.RUnlock()
+	return c.m[infinitive]
+}
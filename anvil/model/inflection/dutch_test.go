@@ -21,7 +21,7 @@ func TestConjugate_detectInfinitive(t *testing.T) {
 	db, dutch := makeDutch()
 	infinitive := "krijgen"
 
-	err := dutch.Conjugate(infinitive, "{{nl-verb}}")
+	err := dutch.Conjugate(1, infinitive, "{{nl-verb}}")
 	check(t, err)
 
 	if db.Words[db.InfinitiveId] != infinitive {
@@ -32,6 +32,59 @@ func TestConjugate_detectInfinitive(t *testing.T) {
 	}
 }
 
+// Dutch.Conjugate should store the non-finite forms and auxiliary that a
+// headword template defines.
+func TestConjugate_headword(t *testing.T) {
+	db, dutch := makeDutch()
+
+	err := dutch.Conjugate(1, "krijgen",
+		"{{nl-verb|kreeg|gekregen|pres_ptc=krijgend|aux=hebben}}")
+	check(t, err)
+
+	if db.Plural != "krijgen" {
+		t.Error("Dutch not setting present plural to infinitive")
+	}
+	if len(db.Infinitives) != 1 {
+		t.Fatal("Dutch did not store the infinitive")
+	}
+
+	infinitive := db.Infinitives[0]
+	if infinitive.PastParticiple != "gekregen" ||
+		infinitive.PresentParticiple != "krijgend" ||
+		infinitive.Gerund != "krijgen" {
+		t.Error("Dutch did not identify the non-finite forms")
+	}
+	if infinitive.Auxiliary != "hebben" {
+		t.Error("Expected auxiliary 'hebben', got", infinitive.Auxiliary)
+	}
+	if infinitive.PageId != 1 {
+		t.Error("Dutch did not record the page of the infinitive")
+	}
+	if infinitive.Class != model.Strong {
+		t.Error("Expected 'krijgen' to be a strong verb, got", infinitive.Class)
+	}
+}
+
+// Dutch.Conjugate should class verbs by the past forms of their headword.
+func TestConjugate_verbClass(t *testing.T) {
+	classes := map[string]model.VerbClass{
+		"{{nl-verb|werkte|gewerkt}}":  model.Weak,
+		"{{nl-verb|leefde|geleefd}}":  model.Weak,
+		"{{nl-verb|kreeg|gekregen}}":  model.Strong,
+		"{{nl-verb|lachte|gelachen}}": model.Mixed,
+		"{{nl-verb|bracht|gebracht}}": model.Irregular,
+		"{{nl-verb|zei|gezegd}}":      model.Irregular,
+		"{{nl-verb}}":                 0,
+	}
+	for template, class := range classes {
+		db, dutch := makeDutch()
+		check(t, dutch.Conjugate(1, "verb", template))
+		if len(db.Infinitives) != 1 || db.Infinitives[0].Class != class {
+			t.Error("Expected", template, "to have class", class)
+		}
+	}
+}
+
 // Dutch.Conjugate should extract the grammatical person from a template
 // and convert it to a column name that matches both the context of use
 // and naming convention used by the database.
@@ -39,7 +92,7 @@ func TestConjugate_identify_person(t *testing.T) {
 	db, dutch := makeDutch()
 	word := "krijg"
 
-	err := dutch.Conjugate(word, "{{nl-verb form of|p=1|n=sg|t=pres|m=ind|krijgen}}")
+	err := dutch.Conjugate(1, word, "{{nl-verb form of|p=1|n=sg|t=pres|m=ind|krijgen}}")
 	check(t, err)
 
 	if db.First != word || db.Second == word || db.Third == word {
@@ -51,14 +104,14 @@ func TestConjugate_identify_person(t *testing.T) {
 func TestConjugate_identify_tense(t *testing.T) {
 	db, dutch := makeDutch()
 
-	err := dutch.Conjugate("kreeg", "{{nl-verb form of|n=sg|t=past|m=ind|krijgen}}")
+	err := dutch.Conjugate(1, "kreeg", "{{nl-verb form of|n=sg|t=past|m=ind|krijgen}}")
 	check(t, err)
 
 	if db.Tense != "past" {
 		t.Error("Expected 'past', got", db.Tense)
 	}
 
-	err = dutch.Conjugate("krijg", "{{nl-verb form of|p=1|n=sg|t=pres|m=ind|krijgen}}")
+	err = dutch.Conjugate(1, "krijg", "{{nl-verb form of|p=1|n=sg|t=pres|m=ind|krijgen}}")
 	check(t, err)
 
 	if db.Tense != "present" {
@@ -66,13 +119,48 @@ func TestConjugate_identify_tense(t *testing.T) {
 	}
 }
 
-// Dutch.Conjugate should only attempt to conjugate the indicative mood for now.
+// Dutch.Conjugate should identify the mood of a verb and treat imperatives
+// as second person forms.
+func TestConjugate_identify_mood(t *testing.T) {
+	db, dutch := makeDutch()
+
+	err := dutch.Conjugate(1, "krijge", "{{nl-verb form of|n=sg|t=pres|m=subj|krijgen}}")
+	check(t, err)
+	if db.Mood != model.Subjunctive {
+		t.Error("Dutch did not identify the subjunctive mood")
+	}
+
+	err = dutch.Conjugate(1, "krijg", "{{nl-verb form of|n=sg|m=imp|krijgen}}")
+	check(t, err)
+	if db.Mood != model.Imperative || db.Second != "krijg" || db.First == "krijg" {
+		t.Error("Dutch did not identify the imperative mood")
+	}
+}
+
+// Dutch.Conjugate should not conjugate moods it does not recognize.
 func TestConjugate_check_mood(t *testing.T) {
 	db, dutch := makeDutch()
-	dutch.Conjugate("krijgt", "{{nl-verb form of|n=pl|m=imp|krijgen}}")
+	dutch.Conjugate(1, "krijgt", "{{nl-verb form of|n=pl|m=xyz|krijgen}}")
 
 	if db.TableAccessCount != 0 {
-		t.Error("Dutch attempted to conjugate mood other than indicative")
+		t.Error("Dutch attempted to conjugate an unknown mood")
+	}
+}
+
+// Dutch.Conjugate should store words under the Dutch language.
+func TestConjugate_wordLanguage(t *testing.T) {
+	db, dutch := makeDutch()
+	dutch.GetLanguage().Id = 7
+
+	check(t, dutch.Conjugate(1, "krijg", "{{nl-verb form of|p=1|n=sg|t=pres|m=ind|krijgen}}"))
+
+	if len(db.WordLanguages) == 0 {
+		t.Fatal("Dutch did not store any words")
+	}
+	for _, languageId := range db.WordLanguages {
+		if languageId != 7 {
+			t.Error("Expected words in language 7, got", languageId)
+		}
 	}
 }
 
@@ -96,20 +184,29 @@ type mockDB struct {
 	InfinitiveId     int
 	First            string
 	Tense            string
+	Mood             model.GrammaticalMood
 	Second           string
 	Third            string
 	Plural           string
 	TableAccessCount int
+	Infinitives      []*model.Infinitive
+	WordLanguages    []int
 }
 
 func (db *mockDB) InsertLanguage(*model.Language) error { return nil }
-func (db *mockDB) InsertWord(word string) (wordId int) {
+func (db *mockDB) InsertWord(languageId int, word string) (wordId int) {
+	db.WordLanguages = append(db.WordLanguages, languageId)
 	db.Words = append(db.Words, word)
 	return len(db.Words) - 1
 }
+func (db *mockDB) InsertInfinitive(infinitive *model.Infinitive) error {
+	db.Infinitives = append(db.Infinitives, infinitive)
+	return nil
+}
 func (db *mockDB) InsertVerbForm(verb *model.VerbForm) error {
 	db.TableAccessCount++
 	db.InfinitiveId = verb.InfinitiveId
+	db.Mood = verb.Mood
 	if verb.Tense == model.Present {
 		db.Tense = "present"
 	} else if verb.Tense == model.Past {
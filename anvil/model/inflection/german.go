@@ -0,0 +1,308 @@
+package inflection
+
+import (
+	"errors"
+	"mutably/anvil/model"
+	"strings"
+)
+
+// German is an implementation of Conjugator for the German language.
+//
+// German verbs are described by two kinds of templates. Infinitives use a
+// headword such as {{de-verb-strong|fängt an|fing an|angefangen|class=7}},
+// which tells us the verb class, any separable prefix, the past participle,
+// and the perfect auxiliary. Finite forms use
+// {{de-verb form of|infinitive|person|number|tense|prefix}}.
+//
+// This struct is guaranteed to be thread safe if instantiated using the
+// NewGerman() creational function.
+type German struct {
+	language *model.Language
+	database model.Database
+
+	// A cache of word ids for infinitive verbs
+	idCache cache
+}
+
+// NewGerman creates and returns a new German instance.
+func NewGerman() *German {
+	return &German{
+		language: model.NewLanguage("German"),
+		idCache:  cache{m: make(map[string]int)},
+	}
+}
+
+// GetLanguage returns the English name of the German language.
+func (german *German) GetLanguage() *model.Language {
+	return german.language
+}
+
+// SetDatabase assigns to german a non-nil database where it stores results.
+func (german *German) SetDatabase(db model.Database) error {
+	if db == nil {
+		return errors.New("German conjugator was given nil database object")
+	}
+	german.database = db
+	return nil
+}
+
+// Conjugate uses a verb's template to construct parts of the conjugation
+// table that it belongs to.
+func (german *German) Conjugate(pageId int, verb, template string) error {
+	tmpl, ok := parseTemplate(template)
+	if !ok {
+		return errors.New("Invalid template for verb " + verb)
+	}
+
+	switch {
+	case tmpl.name == "de-verb form of":
+		return german.handleFinite(pageId, verb, &tmpl)
+	case tmpl.name == "de-verb-form" || tmpl.name == "head":
+		// These only mark the section as one that holds finite forms.
+		return nil
+	case strings.HasPrefix(tmpl.name, "de-verb"):
+		return german.handleInfinitive(pageId, verb, &tmpl)
+	default:
+		return errors.New("Unsupported template " + template)
+	}
+}
+
+// handleInfinitive manages an infinitive verb and its headword template.
+func (german *German) handleInfinitive(pageId int, verb string,
+	tmpl *wikiTemplate) error {
+	class, subclass := getVerbClass(tmpl)
+	infinitive := &model.Infinitive{
+		LanguageId: german.GetLanguage().Id,
+		WordId:     german.storeInfinitive(verb),
+		Class:      class,
+		Subclass:   subclass,
+		Prefix:     getSeparablePrefix(verb, tmpl),
+		PageId:     pageId,
+	}
+	infinitive.PastParticiple, infinitive.Auxiliary = getPerfect(tmpl)
+	for _, word := range []string{infinitive.PastParticiple,
+		infinitive.Auxiliary} {
+		if word != "" {
+			german.database.InsertWord(german.GetLanguage().Id, word)
+		}
+	}
+	if err := german.database.InsertInfinitive(infinitive); err != nil {
+		return err
+	}
+
+	// 'sein' is the only verb whose 1st and 3rd person plural present forms
+	// differ from the infinitive. Its forms have their own pages.
+	if verb == "sein" {
+		return nil
+	}
+
+	// The 1st and 3rd person plural present forms match the infinitive,
+	// with the prefix moved to the end for separable verbs.
+	plural := verb
+	if infinitive.Prefix != "" {
+		plural = strings.TrimPrefix(verb, infinitive.Prefix) + " " +
+			infinitive.Prefix
+		german.database.InsertWord(german.GetLanguage().Id, plural)
+	}
+	return german.database.InsertVerbForm(&model.VerbForm{
+		LanguageId:   german.GetLanguage().Id,
+		InfinitiveId: infinitive.WordId,
+		Word:         plural,
+		Tense:        model.Present,
+		Number:       model.Plural,
+		Person:       model.First | model.Third,
+		PageId:       pageId,
+	})
+}
+
+// handleFinite manages a finite verb form.
+//
+// The arguments of tmpl are, in order: the infinitive, the person (1, 2, 3,
+// or i for imperative), the number (s or p), the tense (g for present, v for
+// past, or k1/k2 for subjunctive), and optionally a separable prefix or 'a'
+// for the dependent clause form. Imperatives have no tense argument.
+func (german *German) handleFinite(pageId int, verb string,
+	tmpl *wikiTemplate) error {
+	if len(tmpl.args) < 3 {
+		return errors.New("Invalid template for verb " + verb)
+	}
+
+	number, err := getGermanNumber(tmpl.arg(2))
+	if err != nil {
+		return err
+	}
+
+	// Imperatives only exist in the second person present, so their
+	// templates give neither and move the prefix up one argument.
+	person, tense, mood := model.Second, model.Present, model.Imperative
+	prefixArg := 3
+	if tmpl.arg(1) != "i" {
+		if person, err = getGermanPerson(tmpl.arg(1)); err != nil {
+			return err
+		}
+		if tense, mood, err = getGermanTense(tmpl.arg(3)); err != nil {
+			return err
+		}
+		prefixArg = 4
+	}
+
+	switch prefix := tmpl.arg(prefixArg); prefix {
+	case "":
+	case "a":
+		// Dependent clause forms (e.g., 'anfange') only join the prefix back
+		// to the main clause form, which fills the same slot in the table.
+		return nil
+	default:
+		verb = verb + " " + prefix
+	}
+	german.database.InsertWord(german.GetLanguage().Id, verb)
+
+	return german.database.InsertVerbForm(&model.VerbForm{
+		LanguageId:   german.GetLanguage().Id,
+		InfinitiveId: german.getInfinitiveId(tmpl.arg(0)),
+		Word:         verb,
+		Tense:        tense,
+		Mood:         mood,
+		Number:       number,
+		Person:       person,
+		PageId:       pageId,
+	})
+}
+
+// storeInfinitive adds an infinitive to the database and id cache.
+// The id of the infinitive is returned.
+func (german *German) storeInfinitive(verb string) int {
+	infinitiveId := german.database.InsertWord(german.GetLanguage().Id, verb)
+
+	german.idCache.Lock()
+	german.idCache.m[verb] = infinitiveId
+	german.idCache.Unlock()
+	return infinitiveId
+}
+
+// getInfinitiveId returns the word id of an infinitive, storing the
+// infinitive if it has not been seen yet.
+func (german *German) getInfinitiveId(infinitive string) int {
+	german.idCache.RLock()
+	id, ok := german.idCache.m[infinitive]
+	german.idCache.RUnlock()
+
+	if !ok {
+		id = german.storeInfinitive(infinitive)
+	}
+	return id
+}
+
+// getVerbClass extracts the verb class and subclass from a headword
+// template. Older templates name the class (e.g., de-verb-strong) while
+// newer ones use the class parameter. Strong verbs may give their ablaut
+// series (e.g., class=3a) instead, which becomes the subclass.
+func getVerbClass(tmpl *wikiTemplate) (model.VerbClass, string) {
+	class := strings.TrimPrefix(tmpl.name, "de-verb-")
+	if class == tmpl.name {
+		class = tmpl.params["class"]
+	}
+
+	var verbClass model.VerbClass
+	switch class {
+	case "weak":
+		verbClass = model.Weak
+	case "strong":
+		verbClass = model.Strong
+	case "mixed":
+		verbClass = model.Mixed
+	case "irregular":
+		verbClass = model.Irregular
+	}
+
+	series := tmpl.params["class"]
+	if series == "" || series[0] < '1' || series[0] > '7' {
+		return verbClass, ""
+	}
+	return model.Strong, series
+}
+
+// getPerfect extracts the past participle and perfect auxiliary from a
+// headword template. Its arguments are the 3rd person singular present, the
+// past, and the past participle. The auxiliary is 'haben' unless the
+// auxiliary parameter names another; verbs that take both (e.g.,
+// 'haben/sein') keep the first. Headwords that don't list the participle
+// give neither.
+func getPerfect(tmpl *wikiTemplate) (participle, auxiliary string) {
+	participle = strings.TrimSpace(tmpl.arg(2))
+	if participle == "" {
+		return "", ""
+	}
+
+	auxiliary = "haben"
+	if aux := strings.FieldsFunc(tmpl.params["auxiliary"], func(r rune) bool {
+		return r == '/' || r == ',' || r == ' '
+	}); len(aux) != 0 {
+		auxiliary = aux[0]
+	}
+	return participle, auxiliary
+}
+
+// getSeparablePrefix finds the separable prefix of an infinitive.
+// Headwords of separable verbs list finite forms with the prefix detached
+// (e.g., 'fängt an'), so the prefix is the trailing word of such a form.
+func getSeparablePrefix(infinitive string, tmpl *wikiTemplate) string {
+	for _, arg := range tmpl.args {
+		words := strings.Fields(arg)
+		if len(words) != 2 {
+			continue
+		}
+
+		prefix := words[1]
+		if strings.HasPrefix(infinitive, prefix) && infinitive != prefix {
+			return prefix
+		}
+	}
+	return ""
+}
+
+// getGermanPerson converts the person argument of a finite template.
+func getGermanPerson(arg string) (model.GrammaticalPerson, error) {
+	switch arg {
+	case "1":
+		return model.First, nil
+	case "2":
+		return model.Second, nil
+	case "3":
+		return model.Third, nil
+	default:
+		return 0, errors.New("Invalid person " + arg)
+	}
+}
+
+// getGermanNumber converts the number argument of a finite template.
+func getGermanNumber(arg string) (model.GrammaticalNumber, error) {
+	switch arg {
+	case "s":
+		return model.Singular, nil
+	case "p":
+		return model.Plural, nil
+	default:
+		return 0, errors.New("Invalid number " + arg)
+	}
+}
+
+// getGermanTense converts the tense argument of a finite template.
+// The subjunctive moods are named after the tense whose stem they use:
+// k1 (Konjunktiv I) is built on the present and k2 (Konjunktiv II) on the
+// past.
+func getGermanTense(arg string) (model.GrammaticalTense,
+	model.GrammaticalMood, error) {
+	switch arg {
+	case "g":
+		return model.Present, model.Indicative, nil
+	case "v":
+		return model.Past, model.Indicative, nil
+	case "k1":
+		return model.Present, model.Subjunctive, nil
+	case "k2":
+		return model.Past, model.Subjunctive, nil
+	default:
+		return 0, 0, errors.New("Invalid tense " + arg)
+	}
+}
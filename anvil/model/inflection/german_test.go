@@ -0,0 +1,156 @@
+package inflection_test
+
+import (
+	"mutably/anvil/model"
+	"mutably/anvil/model/inflection"
+	"testing"
+)
+
+// German.GetLanguage should return a language description.
+func TestGerman_hasLanguageDescription(t *testing.T) {
+	german := inflection.NewGerman()
+	if german.GetLanguage() == nil {
+		t.Error("Conjugators must have at least one language description.")
+	}
+}
+
+// German.Conjugate should detect headword templates and store the infinitive
+// along with its verb class, past participle, and auxiliary. The 1st/3rd
+// person plural present form should be the infinitive.
+func TestGerman_detectInfinitive(t *testing.T) {
+	db, german := makeGerman()
+	infinitive := "machen"
+
+	err := german.Conjugate(1, infinitive, "{{de-verb-weak|macht|machte|gemacht}}")
+	check(t, err)
+
+	if db.Words[db.InfinitiveId] != infinitive {
+		t.Error("German does not detect headword templates")
+	}
+	if db.Plural != infinitive {
+		t.Error("German not setting present plural to infinitive")
+	}
+	if len(db.Infinitives) != 1 || db.Infinitives[0].Class != model.Weak {
+		t.Fatal("German did not identify the weak verb class")
+	}
+	if db.Infinitives[0].PastParticiple != "gemacht" ||
+		db.Infinitives[0].Auxiliary != "haben" {
+		t.Error("German did not store the past participle and auxiliary")
+	}
+}
+
+// German.Conjugate should store the auxiliary that a headword names along
+// with the past participle as words.
+func TestGerman_auxiliary(t *testing.T) {
+	db, german := makeGerman()
+
+	err := german.Conjugate(1, "fallen",
+		"{{de-verb-strong|fällt|fiel|gefallen|class=7|auxiliary=sein}}")
+	check(t, err)
+
+	if len(db.Infinitives) != 1 || db.Infinitives[0].Auxiliary != "sein" {
+		t.Fatal("Expected auxiliary 'sein'")
+	}
+	stored := make(map[string]bool)
+	for _, word := range db.Words {
+		stored[word] = true
+	}
+	if !stored["gefallen"] || !stored["sein"] {
+		t.Error("German did not store the participle and auxiliary as words")
+	}
+}
+
+// German.Conjugate should read the ablaut series of strong verbs and the
+// prefix of separable verbs from headword templates.
+func TestGerman_separableStrongVerb(t *testing.T) {
+	db, german := makeGerman()
+
+	err := german.Conjugate(1, "anfangen",
+		"{{de-verb-strong|fängt an|fing an|angefangen|class=7}}")
+	check(t, err)
+
+	if len(db.Infinitives) != 1 {
+		t.Fatal("German did not store the infinitive")
+	}
+	infinitive := db.Infinitives[0]
+	if infinitive.Class != model.Strong || infinitive.Subclass != "7" {
+		t.Error("German did not identify the strong verb class")
+	}
+	if infinitive.Prefix != "an" {
+		t.Error("Expected prefix 'an', got", infinitive.Prefix)
+	}
+	if infinitive.PageId != 1 {
+		t.Error("German did not record the page of the infinitive")
+	}
+	if db.Plural != "fangen an" {
+		t.Error("Expected plural 'fangen an', got", db.Plural)
+	}
+}
+
+// German.Conjugate should identify the person, number, and tense of finite
+// verb forms.
+func TestGerman_identifyFinite(t *testing.T) {
+	db, german := makeGerman()
+
+	err := german.Conjugate(1, "machte", "{{de-verb form of|machen|3|s|v}}")
+	check(t, err)
+
+	if db.Third != "machte" || db.First == "machte" {
+		t.Error("German identified the wrong grammatical person")
+	}
+	if db.Tense != "past" {
+		t.Error("Expected 'past', got", db.Tense)
+	}
+
+	err = german.Conjugate(1, "macht", "{{de-verb form of|machen|2|p|g}}")
+	check(t, err)
+
+	if db.Plural != "macht" {
+		t.Error("German identified the wrong grammatical number")
+	}
+	if db.Tense != "present" {
+		t.Error("Expected 'present', got", db.Tense)
+	}
+}
+
+// German.Conjugate should attach the separated prefix to main clause forms
+// and skip dependent clause forms, which fill the same slot.
+func TestGerman_separableForms(t *testing.T) {
+	db, german := makeGerman()
+
+	err := german.Conjugate(1, "fange", "{{de-verb form of|anfangen|1|s|g|an}}")
+	check(t, err)
+
+	if db.First != "fange an" {
+		t.Error("Expected 'fange an', got", db.First)
+	}
+
+	german.Conjugate(1, "anfange", "{{de-verb form of|anfangen|1|s|g|a}}")
+	if db.TableAccessCount != 1 {
+		t.Error("German stored a dependent clause form")
+	}
+}
+
+// German.Conjugate should identify subjunctive and imperative forms.
+func TestGerman_identifyMood(t *testing.T) {
+	db, german := makeGerman()
+
+	err := german.Conjugate(1, "machte", "{{de-verb form of|machen|1|s|k2}}")
+	check(t, err)
+	if db.Mood != model.Subjunctive || db.Tense != "past" {
+		t.Error("German did not identify Konjunktiv II as past subjunctive")
+	}
+
+	err = german.Conjugate(1, "fang", "{{de-verb form of|anfangen|i|s|an}}")
+	check(t, err)
+	if db.Mood != model.Imperative || db.Second != "fang an" {
+		t.Error("German did not identify the imperative")
+	}
+}
+
+func makeGerman() (*mockDB, *inflection.German) {
+	db := &mockDB{}
+	german := inflection.NewGerman()
+	german.SetDatabase(db)
+	return db, german
+}
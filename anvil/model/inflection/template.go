@@ -0,0 +1,68 @@
+package inflection
+
+import "strings"
+
+// wikiTemplate is the parsed form of a template such as
+// {{de-verb form of|gehen|1|s|g}}.
+type wikiTemplate struct {
+	// The template name (e.g., 'de-verb form of')
+	name string
+	// Unnamed arguments in the order they appear
+	args []string
+	// Arguments written as key=value
+	params map[string]string
+}
+
+// parseTemplate splits a raw template into its name and arguments.
+// ok is false if raw is not enclosed in double braces.
+//
+// Pipes that belong to links (e.g., [[gehen|ging]]) or nested templates are
+// not treated as argument separators.
+func parseTemplate(raw string) (tmpl wikiTemplate, ok bool) {
+	if !strings.HasPrefix(raw, "{{") || !strings.HasSuffix(raw, "}}") {
+		return tmpl, false
+	}
+	parts := splitArguments(raw[2 : len(raw)-2])
+
+	tmpl.name = strings.TrimSpace(parts[0])
+	tmpl.params = make(map[string]string)
+	for _, part := range parts[1:] {
+		if eq := strings.IndexRune(part, '='); eq != -1 {
+			key := strings.TrimSpace(part[:eq])
+			tmpl.params[key] = strings.TrimSpace(part[eq+1:])
+		} else {
+			tmpl.args = append(tmpl.args, strings.TrimSpace(part))
+		}
+	}
+	return tmpl, true
+}
+
+// arg returns the unnamed argument at index i or an empty string if there
+// are not that many arguments.
+func (tmpl *wikiTemplate) arg(i int) string {
+	if i < len(tmpl.args) {
+		return tmpl.args[i]
+	}
+	return ""
+}
+
+// splitArguments splits the body of a template on pipes that are not inside
+// of a link or nested template.
+func splitArguments(body string) []string {
+	var parts []string
+	depth, start := 0, 0
+	for i := 0; i < len(body); i++ {
+		switch {
+		case strings.HasPrefix(body[i:], "[[") || strings.HasPrefix(body[i:], "{{"):
+			depth++
+			i++
+		case (strings.HasPrefix(body[i:], "]]") || strings.HasPrefix(body[i:], "}}")) && depth > 0:
+			depth--
+			i++
+		case body[i] == '|' && depth == 0:
+			parts = append(parts, body[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, body[start:])
+}
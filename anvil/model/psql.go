@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	_ "github.com/lib/pq"
+	"strings"
 	"time"
 )
 
@@ -46,26 +47,41 @@ func NewPsqlDB(key KeyRing) (*PsqlDB, error) {
 func (db *PsqlDB) prepareStatments() error {
 	var err error
 	db.insertPluralVerb, err = db.Prepare(`
-		INSERT INTO verb_forms (lang_id, word_id, inf_id, tense_id, num)
-		VALUES
-		  ($1,
-		  (SELECT id FROM words WHERE word = $2),
-		  $3,
+		INSERT INTO verb_forms
+		  (lang_id, word_id, inf_id, tense_id, mood_id, person, num, page_id)
+		SELECT * FROM (VALUES
+		  ($1::int,
+		  (SELECT id FROM words WHERE lang_id = $1 AND word = $2),
+		  $3::int,
 		  (SELECT id FROM tenses WHERE tense = $4),
-		  2)
+		  (SELECT id FROM moods WHERE mood = $5),
+		  NULLIF($6::int, 0),
+		  2,
+		  NULLIF($7::int, 0))
+		) AS vf (lang_id, word_id, inf_id, tense_id, mood_id, person, num,
+		  page_id)
+		WHERE ` + notDeleted + `
+		ON CONFLICT DO NOTHING
 	`)
 	if err != nil {
 		return err
 	}
 	db.insertSingularVerb, err = db.Prepare(`
-		INSERT INTO verb_forms (lang_id, word_id, inf_id, tense_id, person, num)
-		VALUES
-		  ($1,
-		  (SELECT id FROM words WHERE word = $2),
-		  $3,
+		INSERT INTO verb_forms
+		  (lang_id, word_id, inf_id, tense_id, mood_id, person, num, page_id)
+		SELECT * FROM (VALUES
+		  ($1::int,
+		  (SELECT id FROM words WHERE lang_id = $1 AND word = $2),
+		  $3::int,
 		  (SELECT id FROM tenses WHERE tense = $4),
-		  $5,
-		  1)
+		  (SELECT id FROM moods WHERE mood = $5),
+		  $6::int,
+		  1,
+		  NULLIF($7::int, 0))
+		) AS vf (lang_id, word_id, inf_id, tense_id, mood_id, person, num,
+		  page_id)
+		WHERE ` + notDeleted + `
+		ON CONFLICT DO NOTHING
 	`)
 	if err != nil {
 		return err
@@ -73,6 +89,18 @@ func (db *PsqlDB) prepareStatments() error {
 	return nil
 }
 
+// notDeleted is true for a verb form vf that admins have not deleted or
+// replaced. Their edits leave a tombstone so that imports don't undo them.
+const notDeleted = `NOT EXISTS (
+		  SELECT 1 FROM deleted_verb_forms deleted
+		  WHERE deleted.lang_id  = vf.lang_id
+		  AND   deleted.word_id  = vf.word_id
+		  AND   deleted.inf_id   = vf.inf_id
+		  AND   deleted.tense_id = vf.tense_id
+		  AND   deleted.mood_id  = vf.mood_id
+		  AND   deleted.person   = COALESCE(vf.person, 0)
+		  AND   deleted.num      = vf.num)`
+
 // InsertLanguage adds language to the database and sets its Id field.
 // If language already exists, the insertion is skipped.
 func (db *PsqlDB) InsertLanguage(language *Language) error {
@@ -94,38 +122,300 @@ func (db *PsqlDB) InsertLanguage(language *Language) error {
 	return err
 }
 
-// InsertWord adds word to db if it was not already there.
+// InsertWord adds word to a language in db if it was not already there.
 // The id of the new (or existing) word is returned.
-func (db *PsqlDB) InsertWord(word string) int {
+func (db *PsqlDB) InsertWord(languageId int, word string) int {
+	const selectWord = `SELECT id FROM words WHERE lang_id = $1 AND word = $2`
+
 	var wordId int
-	row := db.QueryRow(`SELECT id FROM words WHERE word = $1`, word)
-	if row.Scan(&wordId) == sql.ErrNoRows {
-		db.QueryRow(`
-			INSERT INTO words (word)
-			VALUES ($1) RETURNING id`,
-			word,
-		).Scan(&wordId)
+	if db.QueryRow(selectWord, languageId, word).Scan(&wordId) != sql.ErrNoRows {
+		return wordId
+	}
+
+	// Another worker may add the word between the select and the insert.
+	// The insert then returns nothing, but the word can be selected again.
+	err := db.QueryRow(`
+		INSERT INTO words (lang_id, word)
+		VALUES ($1, $2)
+		ON CONFLICT (lang_id, word) DO NOTHING
+		RETURNING id`,
+		languageId, word,
+	).Scan(&wordId)
+	if err == sql.ErrNoRows {
+		db.QueryRow(selectWord, languageId, word).Scan(&wordId)
 	}
 	return wordId
 }
 
-// InsertVerbForm adds a verb form to the database.
-// verb should have all fields (except maybe Person) populated.
+// InsertVerbForm adds a verb form to the database if it is not already there
+// and an admin has not deleted it.
+// verb should have all fields (except maybe Person) populated. Plural forms
+// only store a person if the language distinguishes them (e.g., German 'ihr').
 func (db *PsqlDB) InsertVerbForm(verb *VerbForm) error {
-	var tense string
-	if verb.Tense == Present {
-		tense = "present"
-	} else if verb.Tense == Past {
-		tense = "past"
-	}
+	tense, mood := tenseName(verb.Tense), moodName(verb.Mood)
 
 	var err error
 	if verb.Number == Singular {
 		_, err = db.insertSingularVerb.Exec(verb.LanguageId, verb.Word,
-			verb.InfinitiveId, tense, verb.Person)
+			verb.InfinitiveId, tense, mood, verb.Person, verb.PageId)
 	} else {
 		_, err = db.insertPluralVerb.Exec(verb.LanguageId, verb.Word,
-			verb.InfinitiveId, tense)
+			verb.InfinitiveId, tense, mood, verb.Person, verb.PageId)
+	}
+	return err
+}
+
+// The columns of the infinitives table that InsertInfinitive sets
+const infinitiveColumns = `lang_id, word_id, class_id, subclass, prefix,
+		  aux_id, pres_ptc_id, past_ptc_id, gerund_id, page_id`
+
+// onInfinitiveConflict replaces the properties of an existing infinitive.
+const onInfinitiveConflict = `ON CONFLICT (lang_id, word_id) DO UPDATE
+		SET class_id    = EXCLUDED.class_id,
+		    subclass    = EXCLUDED.subclass,
+		    prefix      = EXCLUDED.prefix,
+		    aux_id      = EXCLUDED.aux_id,
+		    pres_ptc_id = EXCLUDED.pres_ptc_id,
+		    past_ptc_id = EXCLUDED.past_ptc_id,
+		    gerund_id   = EXCLUDED.gerund_id,
+		    page_id     = EXCLUDED.page_id`
+
+// InsertInfinitive stores the properties of an infinitive verb.
+// If the infinitive already has properties, they are replaced.
+// Non-finite forms and the auxiliary should already exist as words in the
+// infinitive's language.
+func (db *PsqlDB) InsertInfinitive(infinitive *Infinitive) error {
+	_, err := db.Exec(`
+		INSERT INTO infinitives (`+infinitiveColumns+`)
+		VALUES
+		  ($1,
+		  $2,
+		  (SELECT id FROM verb_classes WHERE class = $3),
+		  NULLIF($4, ''),
+		  NULLIF($5, ''),
+		  (SELECT id FROM words WHERE lang_id = $1 AND word = $6),
+		  (SELECT id FROM words WHERE lang_id = $1 AND word = $7),
+		  (SELECT id FROM words WHERE lang_id = $1 AND word = $8),
+		  (SELECT id FROM words WHERE lang_id = $1 AND word = $9),
+		  NULLIF($10, 0))
+		`+onInfinitiveConflict,
+		infinitive.LanguageId, infinitive.WordId, className(infinitive.Class),
+		infinitive.Subclass, infinitive.Prefix, infinitive.Auxiliary,
+		infinitive.PresentParticiple, infinitive.PastParticiple,
+		infinitive.Gerund, infinitive.PageId,
+	)
+	return err
+}
+
+// naturalKeyIndex creates the unique index that stops verb forms from being
+// stored more than once.
+const naturalKeyIndex = `
+	CREATE UNIQUE INDEX IF NOT EXISTS verb_forms_natural_key ON verb_forms
+	  (lang_id, word_id, inf_id, tense_id, mood_id, COALESCE(person, 0), num)`
+
+// RemoveDuplicates deletes all but the oldest copy of each verb form and
+// then adds the unique index that keeps new copies out. It returns the
+// number of verb forms deleted.
+//
+// Databases created before verb forms had a unique key may contain copies
+// from imports that were run more than once.
+func (db *PsqlDB) RemoveDuplicates() (int64, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := tx.Exec(`
+		DELETE FROM verb_forms
+		WHERE id IN (
+		  SELECT id FROM (
+		    SELECT id, row_number() OVER (
+		      PARTITION BY lang_id, word_id, inf_id, tense_id, mood_id,
+		        COALESCE(person, 0), num
+		      ORDER BY id) AS copy
+		    FROM verb_forms
+		  ) AS copies
+		  WHERE copy > 1)`)
+	if err == nil {
+		_, err = tx.Exec(naturalKeyIndex)
+	}
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// Flush does nothing; PsqlDB writes immediately. It exists so that PsqlDB
+// can be used in place of BatchDB.
+func (db *PsqlDB) Flush() error {
+	return nil
+}
+
+// tenseName returns the name of tense in the tenses table.
+func tenseName(tense GrammaticalTense) string {
+	switch tense {
+	case Present:
+		return "present"
+	case Past:
+		return "past"
+	}
+	return ""
+}
+
+// moodName returns the name of mood in the moods table.
+func moodName(mood GrammaticalMood) string {
+	switch mood {
+	case Indicative:
+		return "indicative"
+	case Subjunctive:
+		return "subjunctive"
+	case Imperative:
+		return "imperative"
+	}
+	return ""
+}
+
+// className returns the name of class in the verb_classes table.
+func className(class VerbClass) string {
+	switch class {
+	case Weak:
+		return "weak"
+	case Strong:
+		return "strong"
+	case Mixed:
+		return "mixed"
+	case Irregular:
+		return "irregular"
 	}
+	return ""
+}
+
+// GetCheckpoint returns the last checkpoint saved for archive or nil if
+// there is none.
+func (db *PsqlDB) GetCheckpoint(archive string) (*Checkpoint, error) {
+	checkpoint := &Checkpoint{Archive: archive}
+	err := db.QueryRow(`
+		SELECT page_id, page_title, byte_offset
+		FROM import_checkpoints
+		WHERE archive = $1`,
+		archive,
+	).Scan(&checkpoint.PageId, &checkpoint.PageTitle, &checkpoint.Offset)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return checkpoint, nil
+}
+
+// SaveCheckpoint stores checkpoint, replacing any earlier checkpoint for
+// the same archive.
+func (db *PsqlDB) SaveCheckpoint(checkpoint *Checkpoint) error {
+	_, err := db.Exec(`
+		INSERT INTO import_checkpoints
+		  (archive, page_id, page_title, byte_offset)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (archive) DO UPDATE
+		SET page_id     = EXCLUDED.page_id,
+		    page_title  = EXCLUDED.page_title,
+		    byte_offset = EXCLUDED.byte_offset,
+		    updated_at  = NOW()`,
+		checkpoint.Archive, checkpoint.PageId, checkpoint.PageTitle,
+		checkpoint.Offset,
+	)
 	return err
 }
+
+// GetPageRevision returns the last revision imported for the page with
+// pageId or nil if the page has not been imported.
+func (db *PsqlDB) GetPageRevision(pageId int) (*PageRevision, error) {
+	revision := &PageRevision{PageId: pageId}
+	err := db.QueryRow(`
+		SELECT title, revision_id, revised_at
+		FROM pages
+		WHERE id = $1`,
+		pageId,
+	).Scan(&revision.Title, &revision.RevisionId, &revision.Timestamp)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return revision, nil
+}
+
+// SavePageRevisions records that revisions were imported, replacing any
+// earlier revisions of the same pages.
+func (db *PsqlDB) SavePageRevisions(revisions []*PageRevision) error {
+	// Keep statements well below the limit of 65535 parameters.
+	const rowsPerStatement = 1000
+
+	for len(revisions) != 0 {
+		count := len(revisions)
+		if count > rowsPerStatement {
+			count = rowsPerStatement
+		}
+
+		// A statement can't update the same page twice, so only the newest
+		// revision of each page is kept.
+		newest := make(map[int]*PageRevision, count)
+		for _, revision := range revisions[:count] {
+			if seen, ok := newest[revision.PageId]; !ok ||
+				seen.RevisionId < revision.RevisionId {
+				newest[revision.PageId] = revision
+			}
+		}
+		revisions = revisions[count:]
+
+		values := make([]string, 0, len(newest))
+		args := make([]interface{}, 0, len(newest)*4)
+		for _, revision := range newest {
+			n := len(args)
+			values = append(values, fmt.Sprintf("($%d, $%d, $%d, $%d)",
+				n+1, n+2, n+3, n+4))
+			args = append(args, revision.PageId, revision.Title,
+				revision.RevisionId, revision.Timestamp)
+		}
+
+		_, err := db.Exec(`
+			INSERT INTO pages (id, title, revision_id, revised_at)
+			VALUES `+strings.Join(values, ", ")+`
+			ON CONFLICT (id) DO UPDATE
+			SET title       = EXCLUDED.title,
+			    revision_id = EXCLUDED.revision_id,
+			    revised_at  = EXCLUDED.revised_at`,
+			args...,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeletePageContent removes the verb forms and infinitive properties that
+// were imported from the page with pageId. Content that other pages (or
+// admins) added for the same words is kept.
+func (db *PsqlDB) DeletePageContent(pageId int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`DELETE FROM verb_forms WHERE page_id = $1`, pageId)
+	if err == nil {
+		_, err = tx.Exec(`DELETE FROM infinitives WHERE page_id = $1`, pageId)
+	}
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
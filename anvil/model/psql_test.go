@@ -0,0 +1,213 @@
+package model_test
+
+import (
+	"mutably/anvil/model"
+	"testing"
+)
+
+// RemoveDuplicates should keep one copy of each verb form, including plural
+// forms without a person, and restore the unique index.
+func TestPsqlDB_RemoveDuplicates(t *testing.T) {
+	db := openTestDB(t)
+	defer db.Close()
+	language := model.NewLanguage("dedupe")
+	if err := db.InsertLanguage(language); err != nil {
+		t.Fatal(err)
+	}
+	defer cleanUp(t, db, language)
+
+	// Copies can only be stored without the index. Removing duplicates
+	// adds it back even if the test fails.
+	if _, err := db.Exec(`DROP INDEX verb_forms_natural_key`); err != nil {
+		t.Fatal(err)
+	}
+	defer db.RemoveDuplicates()
+
+	infinitiveId := db.InsertWord(language.Id, "maken")
+	wordId := db.InsertWord(language.Id, "maak")
+	_, err := db.Exec(`
+		INSERT INTO verb_forms
+		  (lang_id, word_id, inf_id, tense_id, mood_id, person, num)
+		SELECT $1, $2, $3, tenses.id, moods.id, copies.person, copies.num
+		FROM (VALUES (1, 1), (1, 1), (NULL, 2), (NULL, 2), (NULL, 2))
+		  AS copies (person, num),
+		  tenses, moods
+		WHERE tense = 'present' AND mood = 'indicative'`,
+		language.Id, wordId, infinitiveId,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	removed, err := db.RemoveDuplicates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 3 {
+		t.Error("Expected 3 copies to be removed, removed", removed)
+	}
+	if count := countVerbForms(t, db, language); count != 2 {
+		t.Error("Expected 2 verb forms to be left, found", count)
+	}
+
+	var index *string
+	err = db.QueryRow(`SELECT to_regclass('verb_forms_natural_key')::text`).
+		Scan(&index)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if index == nil {
+		t.Error("Expected the unique index to be created")
+	}
+}
+
+// Importing the same verb forms twice should not store them twice, whether
+// or not writes are batched.
+func TestDatabase_importTwice(t *testing.T) {
+	db := openTestDB(t)
+	defer db.Close()
+
+	targets := map[string]func() (importTarget, error){
+		"psql": func() (importTarget, error) { return db, nil },
+		"batch": func() (importTarget, error) {
+			return model.NewBatchDB(db, 100)
+		},
+	}
+	for name, newTarget := range targets {
+		language := model.NewLanguage("twice" + name)
+		if err := db.InsertLanguage(language); err != nil {
+			t.Fatal(err)
+		}
+		defer cleanUp(t, db, language)
+
+		var counts []int
+		for run := 0; run < 2; run++ {
+			target, err := newTarget()
+			if err != nil {
+				t.Fatal(err)
+			}
+			importVerb(t, target, language)
+			release(t, target)
+			counts = append(counts, countVerbForms(t, db, language))
+		}
+		if counts[0] != 3 || counts[1] != counts[0] {
+			t.Errorf("%s: expected 3 verb forms after each import, found %v",
+				name, counts)
+		}
+	}
+}
+
+// Verb forms that an admin deleted should not be imported again, whether or
+// not writes are batched.
+func TestDatabase_skipsDeletedForms(t *testing.T) {
+	db := openTestDB(t)
+	defer db.Close()
+
+	targets := map[string]func() (importTarget, error){
+		"psql": func() (importTarget, error) { return db, nil },
+		"batch": func() (importTarget, error) {
+			return model.NewBatchDB(db, 100)
+		},
+	}
+	for name, newTarget := range targets {
+		language := model.NewLanguage("deleted" + name)
+		if err := db.InsertLanguage(language); err != nil {
+			t.Fatal(err)
+		}
+		defer cleanUp(t, db, language)
+
+		target, err := newTarget()
+		if err != nil {
+			t.Fatal(err)
+		}
+		importVerb(t, target, language)
+
+		// Delete 'maak' the way the API does, leaving a tombstone.
+		_, err = db.Exec(`
+			WITH deleted AS (
+			  DELETE FROM verb_forms vf
+			  USING words
+			  WHERE words.id = vf.word_id AND words.word = 'maak'
+			  AND vf.lang_id = $1
+			  RETURNING vf.*
+			)
+			INSERT INTO deleted_verb_forms
+			  (lang_id, word_id, inf_id, tense_id, mood_id, person, num)
+			SELECT lang_id, word_id, inf_id, tense_id, mood_id,
+			       COALESCE(person, 0), num
+			FROM deleted`,
+			language.Id,
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		importVerb(t, target, language)
+		release(t, target)
+		if count := countVerbForms(t, db, language); count != 2 {
+			t.Errorf("%s: expected 2 verb forms after the deletion, found %d",
+				name, count)
+		}
+	}
+}
+
+// importTarget is a Database that may buffer writes until it is flushed.
+type importTarget interface {
+	model.Database
+	Flush() error
+}
+
+// importVerb stores a verb with singular forms and a plural form that has
+// no person.
+func importVerb(t *testing.T, target importTarget, language *model.Language) {
+	t.Helper()
+	infinitiveId := target.InsertWord(language.Id, "maken")
+	for word, person := range map[string]model.GrammaticalPerson{
+		"maak": model.First, "maakt": model.Third, "maken": 0} {
+		target.InsertWord(language.Id, word)
+
+		number := model.Singular
+		if person == 0 {
+			number = model.Plural
+		}
+		err := target.InsertVerbForm(&model.VerbForm{
+			LanguageId:   language.Id,
+			InfinitiveId: infinitiveId,
+			Word:         word,
+			Tense:        model.Present,
+			Mood:         model.Indicative,
+			Number:       number,
+			Person:       person,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := target.Flush(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// release gives up the words lock if target is a BatchDB, so that the next
+// one can be created.
+func release(t *testing.T, target importTarget) {
+	t.Helper()
+	if batchDB, ok := target.(*model.BatchDB); ok {
+		if err := batchDB.Release(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// countVerbForms returns the number of verb forms stored for language.
+func countVerbForms(t *testing.T, db *model.PsqlDB,
+	language *model.Language) int {
+	t.Helper()
+	var count int
+	err := db.QueryRow(`SELECT count(*) FROM verb_forms WHERE lang_id = $1`,
+		language.Id).Scan(&count)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return count
+}
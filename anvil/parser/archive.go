@@ -0,0 +1,60 @@
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"io"
+	"os"
+)
+
+// archive reads a compressed pages dump.
+type archive struct {
+	io.Reader
+	file *os.File
+}
+
+// Close closes the file that archive reads from.
+func (a *archive) Close() error {
+	return a.file.Close()
+}
+
+// OpenArchive opens the pages dump at filePath for reading.
+//
+// Dumps that are compressed with bzip2 (including multistream dumps) or
+// gzip are detected by their contents rather than extension and are
+// decompressed as they are read. This means the ~700M download can be used
+// without first unpacking it to disk.
+func OpenArchive(filePath string) (io.ReadCloser, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	reader := bufio.NewReader(file)
+	magic, _ := reader.Peek(3)
+
+	switch {
+	case bytes.HasPrefix(magic, []byte("BZh")):
+		// bzip2.Reader moves on to the next stream when one ends, so
+		// multistream archives need no special handling.
+		return &archive{bzip2.NewReader(reader), file}, nil
+
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		decompressor, err := gzip.NewReader(reader)
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+		return &archive{decompressor, file}, nil
+
+	default:
+		// Plain files are returned as is so that they can be seeked.
+		if _, err = file.Seek(0, io.SeekStart); err != nil {
+			file.Close()
+			return nil, err
+		}
+		return file, nil
+	}
+}
@@ -0,0 +1,76 @@
+package parser_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"io/ioutil"
+	"mutably/anvil/parser"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// A one-page document
+const smallDocument = `<mediawiki><page><title>page1</title><revision><text>Sample text</text></revision></page></mediawiki>`
+
+// smallDocument compressed as two concatenated bzip2 streams, the way
+// multistream Wiktionary dumps are built
+const smallDocumentBzip2 = `QlpoOTFBWSZTWamNFy4AAAGZgAAAoAUmrlSAIAAhqeUANqaFMABNFiptTSpAS24RfQ3MjhHmRJsNfF3JFOFCQqY0XLhCWmg5MUFZJlNZt9mh9AAAHBuAQACABQgAJq/dwCAAVFGjIGjTI0Gin6UaaY01G0lCAyfKXKrZoqenxn2qPEToSLvruQwN14G02Xb1wj8XckU4UJC32aH0`
+
+// OpenArchive should read plain, gzip, and (multistream) bzip2 dumps.
+func TestOpenArchive(t *testing.T) {
+	dir, err := ioutil.TempDir("", "archive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	bzipped, _ := base64.StdEncoding.DecodeString(smallDocumentBzip2)
+	archives := map[string][]byte{
+		"pages.xml":     []byte(smallDocument),
+		"pages.xml.bz2": bzipped,
+		"pages.xml.gz":  gzipBytes(t, smallDocument),
+	}
+
+	for name, content := range archives {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, content, 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		archive, err := parser.OpenArchive(path)
+		if err != nil {
+			t.Error(name, err)
+			continue
+		}
+
+		mparser := &mockParser{}
+		if err := parser.ProcessPages(archive, mparser); err != nil {
+			t.Error(name, err)
+		}
+		archive.Close()
+
+		if len(mparser.Pages) != 1 || mparser.Pages[0].Title != "page1" {
+			t.Error("Failed to read the page from", name)
+		}
+	}
+}
+
+// OpenArchive should return an error if the file does not exist.
+func TestOpenArchive_missing(t *testing.T) {
+	if _, err := parser.OpenArchive("not-a-real-archive.xml"); err == nil {
+		t.Error("Expected an error when opening a missing archive")
+	}
+}
+
+func gzipBytes(t *testing.T, content string) []byte {
+	t.Helper()
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	if _, err := writer.Write([]byte(content)); err != nil {
+		t.Fatal(err)
+	}
+	writer.Close()
+	return compressed.Bytes()
+}
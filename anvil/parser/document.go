@@ -1,6 +1,9 @@
 package parser
 
-import "encoding/xml"
+import (
+	"encoding/xml"
+	"time"
+)
 
 // Page defines the XML structure of a wiktionary 'pages' export.
 // Most pages contain information about a word for various languages. A few
@@ -13,18 +16,27 @@ import "encoding/xml"
 type Page struct {
 	XMLName xml.Name `xml:"page"`
 
+	Id       int      `xml:"id"`
 	Title    string   `xml:"title"`
 	Revision Revision `xml:"revision"`
+
+	// The position in the (decompressed) archive that immediately follows
+	// the page. Passing it to ResumePages continues with the next page.
+	EndOffset int64 `xml:"-"`
 }
 
 // Revision defines the XML structure for a version of a page.
 // Certain exports contain multiple revisions, which are copies of a page at
 // each point of its history of modification. That extra data is unneeded.
 // Because we only need one revision, this struct is essentially the contents
-// of a Page.
+// of a Page. When a page has several, the last one in the export is kept.
 type Revision struct {
 	XMLName xml.Name `xml:"revision"`
 
+	// Revision ids increase over time, so newer revisions have larger ids.
+	Id        int       `xml:"id"`
+	Timestamp time.Time `xml:"timestamp"`
+
 	// The contents of the revision (i.e., body of the webpage)
 	Text string `xml:"text"`
 }
@@ -3,6 +3,7 @@ package parser
 import (
 	"encoding/xml"
 	"io"
+	"io/ioutil"
 	"strings"
 )
 
@@ -15,6 +16,36 @@ type Parser interface {
 //
 // See the Page documentation for details on expected structure for pagesFile.
 func ProcessPages(pagesFile io.Reader, parser Parser) error {
+	return processPages(pagesFile, parser, 0)
+}
+
+// ResumePages is like ProcessPages, but it starts reading pagesFile at
+// offset. The offset should be the EndOffset of a Page that was read from
+// the same file, in which case the page after it is the first one parsed.
+//
+// Files that support seeking jump straight to offset. Others, such as
+// compressed archives, are read up to that point without being parsed.
+func ResumePages(pagesFile io.Reader, parser Parser, offset int64) error {
+	var err error
+	if seeker, ok := pagesFile.(io.Seeker); ok {
+		_, err = seeker.Seek(offset, io.SeekStart)
+	} else {
+		_, err = io.CopyN(ioutil.Discard, pagesFile, offset)
+	}
+	if err != nil {
+		return err
+	}
+
+	// The decoder expects the pages to be wrapped in a root element. Give
+	// them a replacement for the one that was skipped.
+	const root = "<mediawiki>"
+	return processPages(io.MultiReader(strings.NewReader(root), pagesFile),
+		parser, offset-int64(len(root)))
+}
+
+// processPages sends each page of pagesFile to a parser. baseOffset is the
+// position in the archive where pagesFile begins.
+func processPages(pagesFile io.Reader, parser Parser, baseOffset int64) error {
 	decoder := xml.NewDecoder(pagesFile)
 	for t, e := decoder.Token(); t != nil; t, e = decoder.Token() {
 		if e != nil {
@@ -27,6 +58,7 @@ func ProcessPages(pagesFile io.Reader, parser Parser) error {
 				if elementType.Name.Local == "page" {
 					var page Page
 					decoder.DecodeElement(&page, &elementType)
+					page.EndOffset = baseOffset + decoder.InputOffset()
 
 					if isSpecialPage(&page.Title) {
 						continue
@@ -1,6 +1,7 @@
 package parser_test
 
 import (
+	"io"
 	"mutably/anvil/parser"
 	"strings"
 	"testing"
@@ -40,6 +41,60 @@ func TestProcessPages(t *testing.T) {
 	}
 }
 
+// ResumePages should start with the page that follows an offset, whether or
+// not the reader supports seeking.
+func TestResumePages(t *testing.T) {
+	mparser := &mockParser{}
+	parser.ProcessPages(strings.NewReader(mockDocument.Content), mparser)
+	if len(mparser.Pages) != mockDocument.PageCount {
+		t.Fatal("Failed to read the document")
+	}
+	offset := mparser.Pages[0].EndOffset
+
+	readers := map[string]io.Reader{
+		"seeker":     strings.NewReader(mockDocument.Content),
+		"non-seeker": struct{ io.Reader }{strings.NewReader(mockDocument.Content)},
+	}
+	for name, reader := range readers {
+		resumed := &mockParser{}
+		if err := parser.ResumePages(reader, resumed, offset); err != nil {
+			t.Error(name, err)
+		}
+
+		if len(resumed.Pages) != 1 || resumed.Pages[0].Id != 2 {
+			t.Error("Expected", name, "to resume at page2")
+		} else if resumed.Pages[0].EndOffset != mparser.Pages[1].EndOffset {
+			t.Error("Expected", name, "to keep offsets relative to the file")
+		}
+	}
+}
+
+// ProcessPages should keep the id and timestamp of the last revision when a
+// page has several.
+func TestProcessPages_revisions(t *testing.T) {
+	mparser := &mockParser{}
+	reader := strings.NewReader(`<mediawiki><page><title>page1</title><id>7</id>
+<revision><id>1</id><timestamp>2017-07-01T09:18:05Z</timestamp><text>old</text></revision>
+<revision><id>2</id><timestamp>2017-07-02T10:00:00Z</timestamp><text>new</text></revision>
+</page></mediawiki>`)
+
+	if err := parser.ProcessPages(reader, mparser); err != nil {
+		t.Fatal(err)
+	}
+	if len(mparser.Pages) != 1 {
+		t.Fatal("Expected 1 page, found", len(mparser.Pages))
+	}
+
+	page := mparser.Pages[0]
+	if page.Id != 7 || page.Revision.Id != 2 || page.Revision.Text != "new" {
+		t.Error("Expected the last revision of page 7, got", page.Id,
+			page.Revision.Id, page.Revision.Text)
+	}
+	if page.Revision.Timestamp.Day() != 2 {
+		t.Error("Failed to read the revision timestamp")
+	}
+}
+
 // A structurally complete document containing two pages.
 var mockDocument = struct {
 	PageCount int
@@ -0,0 +1,57 @@
+// Package revision limits imports to pages that changed since they were
+// last imported.
+package revision
+
+import (
+	"mutably/anvil/model"
+	"mutably/anvil/parser"
+)
+
+// Filter is a parser.Parser that passes pages to another Parser only if
+// they are newer than the revision that was last imported.
+//
+// Before a changed page is passed on, the content that was imported from its
+// old revision is deleted. This keeps verb forms that were removed from the
+// page out of the database.
+//
+// Filter does not record the revisions it passes on. That is left to the
+// caller, which should only do it once the content of a page is written;
+// until then, a page that was lost to a crash is simply processed again.
+type Filter struct {
+	// The number of pages that were passed on
+	PagesChanged int
+
+	store  model.RevisionStore
+	parser parser.Parser
+}
+
+// NewFilter creates a *Filter that sends changed pages to p and looks up
+// revisions in store.
+func NewFilter(store model.RevisionStore, p parser.Parser) *Filter {
+	return &Filter{store: store, parser: p}
+}
+
+// Parse sends page to the underlying Parser if it has not been imported at
+// its current revision.
+func (filter *Filter) Parse(page parser.Page) (bool, error) {
+	last, err := filter.store.GetPageRevision(page.Id)
+	if err != nil {
+		return false, err
+	}
+	if last != nil && last.RevisionId >= page.Revision.Id {
+		return true, nil
+	}
+
+	// Pages from a full import have no recorded revision, so their content
+	// is always cleared. Content is kept by page id, so a page that moved
+	// loses what it stored under its old title too.
+	if err := filter.store.DeletePageContent(page.Id); err != nil {
+		return false, err
+	}
+
+	cont, err := filter.parser.Parse(page)
+	if err == nil {
+		filter.PagesChanged++
+	}
+	return cont, err
+}
@@ -0,0 +1,102 @@
+package revision_test
+
+import (
+	"mutably/anvil/model"
+	"mutably/anvil/parser"
+	"mutably/anvil/parser/revision"
+	"testing"
+)
+
+// Filter should skip pages whose revision was already imported.
+func TestFilter_skipsSeenRevisions(t *testing.T) {
+	store := newMockStore()
+	store.revisions[1] = &model.PageRevision{PageId: 1, Title: "krijgen",
+		RevisionId: 5}
+	mparser := &mockParser{}
+	filter := revision.NewFilter(store, mparser)
+
+	cont, err := filter.Parse(makePage(1, "krijgen", 5))
+	if !cont || err != nil {
+		t.Error("Filter should continue after skipping a page")
+	}
+	if len(mparser.pages) != 0 || len(store.deleted) != 0 {
+		t.Error("Filter should not process a page it has seen")
+	}
+}
+
+// Filter should clear the old content of changed pages before passing them
+// on. Revisions are recorded by the caller once the pages are written.
+func TestFilter_replacesChangedPages(t *testing.T) {
+	store := newMockStore()
+	store.revisions[1] = &model.PageRevision{PageId: 1, Title: "krijgen",
+		RevisionId: 5}
+	mparser := &mockParser{}
+	filter := revision.NewFilter(store, mparser)
+
+	filter.Parse(makePage(1, "krijgen", 6))
+	filter.Parse(makePage(2, "maken", 1))
+
+	if len(mparser.pages) != 2 || filter.PagesChanged != 2 {
+		t.Fatal("Filter did not pass on changed and new pages")
+	}
+	if len(store.deleted) != 2 || store.deleted[0] != 1 {
+		t.Error("Filter did not delete the content of changed pages")
+	}
+	if store.revisions[1].RevisionId != 5 || store.revisions[2] != nil {
+		t.Error("Filter should not record revisions")
+	}
+}
+
+// Filter should delete the content of a renamed page by its id.
+func TestFilter_movedPage(t *testing.T) {
+	store := newMockStore()
+	store.revisions[1] = &model.PageRevision{PageId: 1, Title: "krijge",
+		RevisionId: 5}
+	filter := revision.NewFilter(store, &mockParser{})
+
+	filter.Parse(makePage(1, "krijgen", 6))
+
+	if len(store.deleted) != 1 || store.deleted[0] != 1 {
+		t.Error("Filter did not delete the content of the moved page")
+	}
+}
+
+func makePage(id int, title string, revisionId int) parser.Page {
+	return parser.Page{
+		Id:       id,
+		Title:    title,
+		Revision: parser.Revision{Id: revisionId},
+	}
+}
+
+type mockParser struct {
+	pages []parser.Page
+}
+
+func (m *mockParser) Parse(page parser.Page) (bool, error) {
+	m.pages = append(m.pages, page)
+	return true, nil
+}
+
+type mockStore struct {
+	revisions map[int]*model.PageRevision
+	deleted   []int
+}
+
+func newMockStore() *mockStore {
+	return &mockStore{revisions: make(map[int]*model.PageRevision)}
+}
+
+func (m *mockStore) GetPageRevision(pageId int) (*model.PageRevision, error) {
+	return m.revisions[pageId], nil
+}
+func (m *mockStore) SavePageRevisions(revisions []*model.PageRevision) error {
+	for _, revision := range revisions {
+		m.revisions[revision.PageId] = revision
+	}
+	return nil
+}
+func (m *mockStore) DeletePageContent(pageId int) error {
+	m.deleted = append(m.deleted, pageId)
+	return nil
+}
@@ -0,0 +1,89 @@
+package verb
+
+import (
+	"mutably/anvil/parser"
+	"sync"
+)
+
+// job is a page waiting in the job queue.
+type job struct {
+	// The order in which VerbParser received the page
+	seq  int
+	page parser.Page
+}
+
+// progress tracks which pages have been processed by workers.
+//
+// Workers finish pages out of order, so the last finished page does not mean
+// that every page before it is done. progress only reports a page once all
+// pages that came before it have also finished.
+type progress struct {
+	sync.Mutex
+	// The seq of the earliest page that has not finished
+	next int
+	// Pages that finished before some page that came ahead of them
+	finished map[int]finishedPage
+	// The most recent page that was finished along with all before it
+	last *parser.Page
+	// Pages that were processed without errors, along with all pages before
+	// them, and have not been taken yet
+	processed []parser.Page
+}
+
+// finishedPage is a page that a worker is done with.
+type finishedPage struct {
+	page parser.Page
+	// Whether every template on the page was stored
+	ok bool
+}
+
+func newProgress() *progress {
+	return &progress{finished: make(map[int]finishedPage)}
+}
+
+// finish marks the page of a job as processed. ok is false if some of its
+// content could not be stored.
+func (p *progress) finish(j job, ok bool) {
+	// The text is not needed to describe progress; don't hold on to it.
+	j.page.Revision.Text = ""
+
+	p.Lock()
+	defer p.Unlock()
+
+	p.finished[j.seq] = finishedPage{j.page, ok}
+	for {
+		done, ok := p.finished[p.next]
+		if !ok {
+			break
+		}
+		delete(p.finished, p.next)
+		p.last = &done.page
+		if done.ok {
+			p.processed = append(p.processed, done.page)
+		}
+		p.next++
+	}
+}
+
+// lastPage returns the most recent page that was processed along with all
+// pages before it. ok is false if there is no such page.
+func (p *progress) lastPage() (page parser.Page, ok bool) {
+	p.Lock()
+	defer p.Unlock()
+
+	if p.last == nil {
+		return page, false
+	}
+	return *p.last, true
+}
+
+// takeProcessed returns the pages that were processed without errors since
+// the last call, in the order they were received.
+func (p *progress) takeProcessed() []parser.Page {
+	p.Lock()
+	defer p.Unlock()
+
+	pages := p.processed
+	p.processed = nil
+	return pages
+}
@@ -7,6 +7,7 @@ import (
 	"mutably/anvil/model/inflection"
 	"mutably/anvil/parser"
 	"sync"
+	"sync/atomic"
 )
 
 // VerbParser uses parallel workers to add verbs to a database.
@@ -19,9 +20,16 @@ type VerbParser struct {
 	PagesConsumed int
 
 	// This buffered channel holds Page sent from parse.
-	jobQueue chan parser.Page
+	jobQueue chan job
 	// A wait group for the workers.
 	waitGroup sync.WaitGroup
+
+	// The number of pages sent to the job queue
+	pagesQueued int
+	// Tracks the pages that workers have finished
+	progress *progress
+	// Non-zero once Stop has been called
+	stopped int32
 }
 
 // NewVerbParser creates a *VerbParser that is connected to db and
@@ -48,7 +56,8 @@ func NewVerbParser(db model.Database, threadCount, pageLimit int,
 	vparser := &VerbParser{
 		PageLimit:     pageLimit,
 		PagesConsumed: 0,
-		jobQueue:      make(chan parser.Page, jobQueueSize),
+		jobQueue:      make(chan job, jobQueueSize),
+		progress:      newProgress(),
 	}
 	vparser.storeLanguages(db, conjugators)
 	vparser.spawnWorkers(threadCount, db, conjugators)
@@ -79,7 +88,8 @@ func (vparser *VerbParser) spawnWorkers(workerCount int, db model.Database,
 		go func(wkr worker, wg *sync.WaitGroup) {
 			defer wg.Done()
 			wkr.Start()
-		}(NewWorker(db, vparser.jobQueue, conjugators), &vparser.waitGroup)
+		}(NewWorker(db, vparser.jobQueue, vparser.progress, conjugators),
+			&vparser.waitGroup)
 	}
 }
 
@@ -91,6 +101,34 @@ func (vparser *VerbParser) Wait() {
 	vparser.waitGroup.Wait()
 }
 
+// Stop makes Parse refuse any more pages. Pages that were already received
+// are still processed; call Wait to let them finish.
+//
+// Stop is safe to call from a goroutine other than the one calling Parse.
+func (vparser *VerbParser) Stop() {
+	atomic.StoreInt32(&vparser.stopped, 1)
+}
+
+// LastPage returns the most recent page that workers have finished
+// processing along with every page received before it. Resuming after this
+// page will not miss any content. ok is false if no such page exists yet.
+//
+// The returned page does not include its text.
+func (vparser *VerbParser) LastPage() (page parser.Page, ok bool) {
+	return vparser.progress.lastPage()
+}
+
+// TakeProcessed returns the pages that workers have processed without
+// errors since the last call. Like LastPage, a page is only returned once
+// every page received before it has finished. Pages are returned without
+// their text.
+//
+// Content from the returned pages has been passed to the database, but it may
+// still be buffered there.
+func (vparser *VerbParser) TakeProcessed() []parser.Page {
+	return vparser.progress.takeProcessed()
+}
+
 // Parse searches page for verbs and adds their templates to a database.
 //
 // These templates explain what form the verb is in (e.g., infinitive or
@@ -100,6 +138,9 @@ func (vparser *VerbParser) Wait() {
 // This is a mostly nonblocking call. You should invoke Wait to ensure
 // results ready.
 func (vparser *VerbParser) Parse(page parser.Page) (bool, error) {
+	if atomic.LoadInt32(&vparser.stopped) != 0 {
+		return false, nil
+	}
 	if vparser.PagesConsumed >= vparser.PageLimit &&
 		vparser.PageLimit != -1 {
 		return false, errors.New("VerbParser is no longer accepting Pages.")
@@ -109,7 +150,8 @@ func (vparser *VerbParser) Parse(page parser.Page) (bool, error) {
 	}
 
 	// Send the page to a worker that waits on the other end.
-	vparser.jobQueue <- page
+	vparser.jobQueue <- job{seq: vparser.pagesQueued, page: page}
+	vparser.pagesQueued++
 
 	return true, nil
 }
@@ -1,6 +1,7 @@
 package verb_test
 
 import (
+	"errors"
 	"mutably/anvil/model"
 	"mutably/anvil/model/inflection"
 	"mutably/anvil/parser"
@@ -46,6 +47,77 @@ func TestVerbParser_NewLanguage(t *testing.T) {
 	}
 }
 
+// VerbParser should report the last page that it finished along with all
+// pages before it.
+func TestVerbParser_LastPage(t *testing.T) {
+	vparser, _ := makeMockParser(t)
+
+	if _, ok := vparser.LastPage(); ok {
+		t.Error("No pages should be finished before parsing")
+	}
+
+	for id := 1; id <= 3; id++ {
+		page := mockPage.Page
+		page.Id = id
+		vparser.Parse(page)
+	}
+	vparser.Wait()
+
+	page, ok := vparser.LastPage()
+	if !ok || page.Id != 3 {
+		t.Error("Expected the last page to be 3, got", page.Id)
+	}
+}
+
+// VerbParser should return each page that was processed without errors
+// once, along with its revision.
+func TestVerbParser_TakeProcessed(t *testing.T) {
+	conjugators := make(map[string]inflection.Conjugator)
+	for _, language := range mockPage.Languages {
+		conjugators[language.String()] = &mockConjugator{language: language,
+			failPageId: 2}
+	}
+	vparser, err := verb.NewVerbParser(newMockDB(), 2, -1, conjugators)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for id := 1; id <= 3; id++ {
+		page := mockPage.Page
+		page.Id = id
+		page.Revision.Id = id * 10
+		vparser.Parse(page)
+	}
+	vparser.Wait()
+
+	pages := vparser.TakeProcessed()
+	if len(pages) != 2 || pages[0].Id != 1 || pages[1].Id != 3 {
+		t.Fatal("Expected pages 1 and 3 to be processed, got", pages)
+	}
+	if pages[1].Revision.Id != 30 || pages[1].Revision.Text != "" {
+		t.Error("Processed pages should keep their revision but not its text")
+	}
+	if len(vparser.TakeProcessed()) != 0 {
+		t.Error("Processed pages should only be returned once")
+	}
+}
+
+// VerbParser should refuse new pages after Stop is called.
+func TestVerbParser_Stop(t *testing.T) {
+	vparser, mdb := makeMockParser(t)
+
+	vparser.Stop()
+	cont, err := vparser.Parse(mockPage.Page)
+	vparser.Wait()
+
+	if cont || err != nil {
+		t.Error("Stopped VerbParser should signal a clean stop")
+	}
+	if len(mdb.verbs) != 0 {
+		t.Error("Stopped VerbParser should not process pages")
+	}
+}
+
 func makeMockParser(t *testing.T) (*verb.VerbParser, *mockDB) {
 	t.Helper()
 
@@ -67,6 +139,8 @@ func makeMockParser(t *testing.T) (*verb.VerbParser, *mockDB) {
 type mockConjugator struct {
 	language *model.Language
 	database model.Database
+	// Conjugating templates from this page fails
+	failPageId int
 }
 
 func (m *mockConjugator) GetLanguage() *model.Language {
@@ -76,15 +150,19 @@ func (m *mockConjugator) SetDatabase(db model.Database) error {
 	m.database = db
 	return nil
 }
-func (m *mockConjugator) Conjugate(verb, template string) error {
+func (m *mockConjugator) Conjugate(pageId int, verb, template string) error {
+	if pageId == m.failPageId && pageId != 0 {
+		return errors.New("mock conjugation failed")
+	}
 	err := m.database.InsertVerbForm(&model.VerbForm{Word: verb})
 	return err
 }
 
 type mockDB struct {
-	languages []*model.Language
-	words     []string
-	verbs     []*model.VerbForm
+	languages   []*model.Language
+	words       []string
+	verbs       []*model.VerbForm
+	infinitives []*model.Infinitive
 }
 
 func newMockDB() *mockDB {
@@ -95,7 +173,7 @@ func (m *mockDB) InsertLanguage(language *model.Language) error {
 	m.languages = append(m.languages, language)
 	return nil
 }
-func (m *mockDB) InsertWord(word string) (wordId int) {
+func (m *mockDB) InsertWord(languageId int, word string) (wordId int) {
 	m.words = append(m.words, word)
 	return len(m.words) - 1
 }
@@ -103,6 +181,10 @@ func (m *mockDB) InsertVerbForm(verb *model.VerbForm) error {
 	m.verbs = append(m.verbs, verb)
 	return nil
 }
+func (m *mockDB) InsertInfinitive(infinitive *model.Infinitive) error {
+	m.infinitives = append(m.infinitives, infinitive)
+	return nil
+}
 
 // A mock page setup. Do not change the contents of Page! Many tests in this
 // test package rely on it being the way it is. If you need a different
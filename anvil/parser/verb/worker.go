@@ -33,11 +33,13 @@ type worker struct {
 	// Pattern for matching any verb template
 	templatePattern *regexp.Regexp
 
-	jobQueue chan parser.Page
+	jobQueue chan job
+	// Where finished pages are reported
+	progress *progress
 }
 
 // NewWorker creates a worker ready to accept jobs.
-func NewWorker(db model.Database, jobQueue chan parser.Page,
+func NewWorker(db model.Database, jobQueue chan job, progress *progress,
 	conjugators map[string]inflection.Conjugator) worker {
 	return worker{
 		database:          db,
@@ -48,19 +50,22 @@ func NewWorker(db model.Database, jobQueue chan parser.Page,
 		indicativePattern: regexp.MustCompile(`verb( |-)form`),
 		templatePattern:   regexp.MustCompile(`(?m)(# )?({{[^{]*}})`),
 		jobQueue:          jobQueue,
+		progress:          progress,
 	}
 }
 
 // Start makes worker begin waiting for jobs from the job queue.
 func (wkr worker) Start() {
-	for page := range wkr.jobQueue {
-		wkr.process(page)
+	for j := range wkr.jobQueue {
+		wkr.progress.finish(j, wkr.process(j.page))
 	}
 }
 
 // process extracts from page the language, word, and verb templates. The
-// word and templates are then inserted into wkr.database.
-func (wkr worker) process(page parser.Page) {
+// word and templates are then inserted into wkr.database. stored is false if
+// a template could not be stored.
+func (wkr worker) process(page parser.Page) (stored bool) {
+	stored = true
 	content := &page.Revision.Text
 
 	// Each page defines a word in multiple languages. The definitions are
@@ -91,13 +96,16 @@ func (wkr worker) process(page parser.Page) {
 
 			templates := wkr.getTemplates()
 			for _, template := range templates {
-				err := conjugator.Conjugate(page.Title, template)
+				err := conjugator.Conjugate(page.Id, page.Title,
+					template)
 				if err != nil {
 					log.Println(err)
+					stored = false
 				}
 			}
 		}
 	}
+	return stored
 }
 
 // getTemplates creates a VerbTemplate for each verb template
@@ -5,7 +5,6 @@ import (
 	"github.com/fatih/color"
 	"log"
 	"mutably/anvil/parser"
-	"os"
 )
 
 // PageViewer reads Pages until one with a target title is found.
@@ -26,10 +25,11 @@ func (v *PageViewer) Parse(page parser.Page) (bool, error) {
 // Search looks for a page in filePath that contains title.
 // If found, the page is printed to stdout.
 func Search(filePath, title string) {
-	file, err := os.Open(filePath)
+	file, err := parser.OpenArchive(filePath)
 	if err != nil {
 		log.Fatal(err.Error())
 	}
+	defer file.Close()
 
 	color.Green("Starting search...")
 	err = parser.ProcessPages(file, &PageViewer{title})
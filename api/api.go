@@ -22,6 +22,7 @@ func main() {
 	if err != nil {
 		log.Fatal("Could not start service; ", err)
 	}
+	service.ClientIPHeader = os.Getenv("API_CLIENT_IP_HEADER")
 
 	err = service.Start()
 	if err != nil {
@@ -0,0 +1,112 @@
+package controller
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"mutably/api/model"
+	"mutably/api/model/scheduler"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// Cards is a Controller that handles the /cards resource. Cards schedule
+// the cells of conjugation tables for review by the user whose token
+// authorized the request.
+type Cards struct {
+	db   model.Database
+	auth *model.AuthLayer
+}
+
+func (c *Cards) Routes() []Route {
+	return []Route{
+		{ // GET /v1/cards/due
+			Version:     "v1",
+			Path:        "/cards/due",
+			Method:      "GET",
+			Handler:     c.getDueCards,
+			IsProtected: true,
+		},
+		{ // POST /v1/cards/{id:[0-9]+}/reviews
+			Version:     "v1",
+			Path:        "/cards/{id:[0-9]+}/reviews",
+			Method:      "POST",
+			Handler:     c.reviewCard,
+			IsProtected: true,
+		},
+	}
+}
+
+// GET /api/v1/cards/due?limit=20&new=10
+func (c *Cards) getDueCards(w http.ResponseWriter, r *http.Request) {
+	userId, err := requestUserId(c.auth, r)
+	if err != nil {
+		makeErrorResponse(w, http.StatusForbidden, err.Error())
+		return
+	}
+
+	params := r.URL.Query()
+	const maxLimit = 100
+	limit, err := intParam(params.Get("limit"), 20)
+	if err != nil || limit < 1 || limit > maxLimit {
+		makeErrorResponse(w, http.StatusBadRequest,
+			"limit must be between 1 and "+strconv.Itoa(maxLimit))
+		return
+	}
+	newPerDay, err := intParam(params.Get("new"), 10)
+	if err != nil || newPerDay < 0 || newPerDay > maxLimit {
+		makeErrorResponse(w, http.StatusBadRequest,
+			"new must be between 0 and "+strconv.Itoa(maxLimit))
+		return
+	}
+
+	cards, err := c.db.GetDueCards(userId, limit, newPerDay)
+	if err == model.ErrNoTargetLanguage {
+		makeErrorResponse(w, http.StatusBadRequest,
+			"choose a target language before asking for cards")
+		return
+	}
+	respondWithAggregate(w, cards, len(cards), err, "due cards")
+}
+
+// POST /api/v1/cards/{id:[0-9]+}/reviews
+// {"grade": 4}
+func (c *Cards) reviewCard(w http.ResponseWriter, r *http.Request) {
+	userId, err := requestUserId(c.auth, r)
+	if err != nil {
+		makeErrorResponse(w, http.StatusForbidden, err.Error())
+		return
+	}
+
+	cardId, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		makeErrorResponse(w, http.StatusBadRequest, "invalid card id")
+		return
+	}
+
+	var body struct {
+		Grade *scheduler.Grade `json:"grade"`
+	}
+	if err = json.NewDecoder(r.Body).Decode(&body); err != nil ||
+		body.Grade == nil {
+		makeErrorResponse(w, http.StatusBadRequest,
+			`expected a body of the form {"grade": 0-5}`)
+		return
+	}
+
+	card, err := c.db.ReviewCard(userId, cardId, *body.Grade)
+	switch {
+	case err == scheduler.ErrInvalidGrade:
+		makeErrorResponse(w, http.StatusBadRequest, err.Error())
+	case err == sql.ErrNoRows:
+		makeErrorResponse(w, http.StatusNotFound, "card not found")
+	case err != nil:
+		log.Println(err)
+		makeErrorResponse(w, http.StatusInternalServerError,
+			"could not record review")
+	default:
+		makeJsonResponse(w, http.StatusOK, card)
+	}
+}
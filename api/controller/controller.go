@@ -1,6 +1,10 @@
 package controller
 
-import "net/http"
+import (
+	"mutably/api/controller/ratelimit"
+	"mutably/api/model"
+	"net/http"
+)
 
 // Route defines how a REST resource is accessed.
 type Route struct {
@@ -9,6 +13,14 @@ type Route struct {
 	Method      string
 	Handler     http.HandlerFunc
 	IsProtected bool
+
+	// The role that a token must claim to access the route, if any. Routes
+	// with a role are protected even if IsProtected is false.
+	Role model.Role
+
+	// How many requests each client can make to the route. Clients are
+	// identified by the user id of their token or else by IP address.
+	Limit ratelimit.Limit
 }
 
 // A Controller connects a view (HTTP responses) with the
@@ -0,0 +1,167 @@
+package controller
+
+import (
+	"encoding/json"
+	"mutably/api/model"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// Corrections is a Controller that handles verb form corrections. Any user
+// can propose one, and admins review them from a queue.
+type Corrections struct {
+	db   model.Database
+	auth *model.AuthLayer
+}
+
+func (cs *Corrections) Routes() []Route {
+	return []Route{
+		{ // POST /v1/words/{word}/corrections
+			Version:     "v1",
+			Path:        "/words/{word}/corrections",
+			Method:      "POST",
+			Handler:     cs.createCorrection,
+			IsProtected: true,
+		},
+		{ // GET /v1/corrections
+			Version: "v1",
+			Path:    "/corrections",
+			Method:  "GET",
+			Handler: cs.getCorrections,
+			Role:    model.RoleAdmin,
+		},
+		{ // GET /v1/corrections/{id:[0-9]+}
+			Version: "v1",
+			Path:    "/corrections/{id:[0-9]+}",
+			Method:  "GET",
+			Handler: cs.getCorrection,
+			Role:    model.RoleAdmin,
+		},
+		{ // PATCH /v1/corrections/{id:[0-9]+}
+			Version: "v1",
+			Path:    "/corrections/{id:[0-9]+}",
+			Method:  "PATCH",
+			Handler: cs.reviewCorrection,
+			Role:    model.RoleAdmin,
+		},
+	}
+}
+
+// POST /api/v1/words/{word}/corrections
+// {"language": 1, "replaces": 12, "word": "kreeg", "tense": "past",
+// "mood": "indicative", "persons": [1, 2, 3], "number": "singular",
+// "comment": "..."}
+//
+// The verb is {word}. Without replaces, the form is proposed as a new one.
+func (cs *Corrections) createCorrection(w http.ResponseWriter,
+	r *http.Request) {
+	submitterId, err := requestUserId(cs.auth, r)
+	if err != nil {
+		makeErrorResponse(w, http.StatusForbidden, err.Error())
+		return
+	}
+
+	var body struct {
+		model.VerbForm
+		Replaces int    `json:"replaces"`
+		Comment  string `json:"comment"`
+	}
+	if err = json.NewDecoder(r.Body).Decode(&body); err != nil ||
+		body.Word == "" || body.Tense == "" || body.Replaces < 0 {
+		makeErrorResponse(w, http.StatusBadRequest,
+			"expected a verb form with a word, tense, and number")
+		return
+	}
+	body.Infinitive = mux.Vars(r)["word"]
+
+	created, err := cs.db.CreateCorrection(submitterId, &model.Correction{
+		Replaces: body.Replaces,
+		Form:     body.VerbForm,
+		Comment:  body.Comment,
+	})
+	if err == nil {
+		w.Header().Set("Location",
+			"/api/v1/corrections/"+strconv.Itoa(created.Id))
+	}
+	respondWithEdit(w, http.StatusCreated, created, err, "correction")
+}
+
+// GET /api/v1/corrections?status=pending&language=1&limit=20&cursor=MjA
+//
+// Corrections are listed oldest first unless sort is given.
+func (cs *Corrections) getCorrections(w http.ResponseWriter,
+	r *http.Request) {
+	opts, err := parseListOptions(r, model.CorrectionSortFields)
+	if err != nil {
+		makeErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "":
+		status = model.CorrectionPending
+	case model.CorrectionPending, model.CorrectionAccepted,
+		model.CorrectionRejected:
+	default:
+		makeErrorResponse(w, http.StatusBadRequest,
+			"status must be pending, accepted, or rejected")
+		return
+	}
+
+	corrections, err := cs.db.GetCorrections(status, peek(opts))
+	respondWithPage(w, r, corrections, opts, err, "corrections")
+}
+
+// GET /api/v1/corrections/{id:[0-9]+}
+func (cs *Corrections) getCorrection(w http.ResponseWriter, r *http.Request) {
+	correctionId, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		makeErrorResponse(w, http.StatusBadRequest, "invalid correction id")
+		return
+	}
+
+	correction, err := cs.db.GetCorrection(correctionId)
+	if err != nil {
+		makeErrorResponse(w, http.StatusNotFound, "correction not found")
+	} else {
+		makeJsonResponse(w, http.StatusOK, correction)
+	}
+}
+
+// PATCH /api/v1/corrections/{id:[0-9]+}
+// {"status": "accepted", "note": "..."}
+//
+// Accepting a correction applies its form to the conjugation of the verb.
+func (cs *Corrections) reviewCorrection(w http.ResponseWriter,
+	r *http.Request) {
+	reviewerId, err := requestUserId(cs.auth, r)
+	if err != nil {
+		makeErrorResponse(w, http.StatusForbidden, err.Error())
+		return
+	}
+
+	correctionId, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		makeErrorResponse(w, http.StatusBadRequest, "invalid correction id")
+		return
+	}
+
+	var body struct {
+		Status string `json:"status"`
+		Note   string `json:"note"`
+	}
+	if err = json.NewDecoder(r.Body).Decode(&body); err != nil ||
+		body.Status != model.CorrectionAccepted &&
+			body.Status != model.CorrectionRejected {
+		makeErrorResponse(w, http.StatusBadRequest,
+			`expected a body of the form {"status": "accepted" or "rejected"}`)
+		return
+	}
+
+	reviewed, err := cs.db.ReviewCorrection(reviewerId, correctionId,
+		body.Status == model.CorrectionAccepted, body.Note)
+	respondWithEdit(w, http.StatusOK, reviewed, err, "correction")
+}
@@ -0,0 +1,168 @@
+package controller
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"mutably/api/model"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// Drills is a Controller that handles the /drills resource. Drills belong
+// to the user whose token authorized the request.
+type Drills struct {
+	db   model.Database
+	auth *model.AuthLayer
+}
+
+func (d *Drills) Routes() []Route {
+	return []Route{
+		{ // GET /v1/drills
+			Version:     "v1",
+			Path:        "/drills",
+			Method:      "GET",
+			Handler:     d.getDrills,
+			IsProtected: true,
+		},
+		{ // POST /v1/drills
+			Version:     "v1",
+			Path:        "/drills",
+			Method:      "POST",
+			Handler:     d.createDrill,
+			IsProtected: true,
+		},
+		{ // GET /v1/drills/{id:[0-9]+}
+			Version:     "v1",
+			Path:        "/drills/{id:[0-9]+}",
+			Method:      "GET",
+			Handler:     d.getDrill,
+			IsProtected: true,
+		},
+		{ // POST /v1/drills/{id:[0-9]+}/answers
+			Version:     "v1",
+			Path:        "/drills/{id:[0-9]+}/answers",
+			Method:      "POST",
+			Handler:     d.answerDrill,
+			IsProtected: true,
+		},
+	}
+}
+
+// requestUserId returns the id of the user whose token authorized r.
+func requestUserId(auth *model.AuthLayer, r *http.Request) (string, error) {
+	claims, err := auth.GetClaims(r)
+	if err != nil {
+		return "", err
+	}
+	id, _ := claims["id"].(string)
+	return id, nil
+}
+
+// GET /api/v1/drills?limit=20&cursor=MjA&sort=-created&language=1
+func (d *Drills) getDrills(w http.ResponseWriter, r *http.Request) {
+	userId, err := requestUserId(d.auth, r)
+	if err != nil {
+		makeErrorResponse(w, http.StatusForbidden, err.Error())
+		return
+	}
+
+	opts, err := parseListOptions(r, model.DrillSortFields)
+	if err != nil {
+		makeErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	drills, err := d.db.GetDrills(userId, peek(opts))
+	respondWithPage(w, r, drills, opts, err, "drills")
+}
+
+// POST /api/v1/drills
+func (d *Drills) createDrill(w http.ResponseWriter, r *http.Request) {
+	userId, err := requestUserId(d.auth, r)
+	if err != nil {
+		makeErrorResponse(w, http.StatusForbidden, err.Error())
+		return
+	}
+
+	drill, err := d.db.CreateDrill(userId)
+	switch {
+	case err == model.ErrNoTargetLanguage:
+		makeErrorResponse(w, http.StatusBadRequest,
+			"choose a target language before asking for drills")
+	case err == sql.ErrNoRows:
+		makeErrorResponse(w, http.StatusNotFound,
+			"no verbs exist in the target language")
+	case err != nil:
+		log.Println(err)
+		makeErrorResponse(w, http.StatusInternalServerError,
+			"could not create drill")
+	default:
+		w.Header().Set("Location", "/api/v1/drills/"+strconv.Itoa(drill.Id))
+		makeJsonResponse(w, http.StatusCreated, drill)
+	}
+}
+
+// GET /api/v1/drills/{id:[0-9]+}
+func (d *Drills) getDrill(w http.ResponseWriter, r *http.Request) {
+	userId, err := requestUserId(d.auth, r)
+	if err != nil {
+		makeErrorResponse(w, http.StatusForbidden, err.Error())
+		return
+	}
+
+	drillId, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		makeErrorResponse(w, http.StatusBadRequest, "invalid drill id")
+		return
+	}
+
+	drill, err := d.db.GetDrill(userId, drillId)
+	if err != nil {
+		makeErrorResponse(w, http.StatusNotFound, "drill not found")
+	} else {
+		makeJsonResponse(w, http.StatusOK, drill)
+	}
+}
+
+// POST /api/v1/drills/{id:[0-9]+}/answers
+// {"answer": "kreeg"}
+func (d *Drills) answerDrill(w http.ResponseWriter, r *http.Request) {
+	userId, err := requestUserId(d.auth, r)
+	if err != nil {
+		makeErrorResponse(w, http.StatusForbidden, err.Error())
+		return
+	}
+
+	drillId, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		makeErrorResponse(w, http.StatusBadRequest, "invalid drill id")
+		return
+	}
+
+	var body struct {
+		Answer string `json:"answer"`
+	}
+	if err = json.NewDecoder(r.Body).Decode(&body); err != nil ||
+		body.Answer == "" {
+		makeErrorResponse(w, http.StatusBadRequest,
+			`expected a body of the form {"answer": "..."}`)
+		return
+	}
+
+	result, err := d.db.AnswerDrill(userId, drillId, body.Answer)
+	switch {
+	case err == sql.ErrNoRows:
+		makeErrorResponse(w, http.StatusNotFound, "drill not found")
+	case err == model.ErrDrillAnswered:
+		makeErrorResponse(w, http.StatusConflict, err.Error())
+	case err != nil:
+		log.Println(err)
+		makeErrorResponse(w, http.StatusInternalServerError,
+			"could not check answer")
+	default:
+		makeJsonResponse(w, http.StatusOK, result)
+	}
+}
@@ -8,10 +8,12 @@ import (
 	"fmt"
 	"log"
 	"mutably/api/controller"
+	"mutably/api/controller/ratelimit"
 	"mutably/api/model"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strconv"
 	"testing"
 
 	"github.com/satori/go.uuid"
@@ -72,10 +74,20 @@ func clearTable(t *testing.T, table string) {
 	checkError(t, err)
 }
 
-// clearDatabase returns the test database to the default empty state.
+// clearDatabase returns the test database and rate limits to the default
+// empty state.
 func clearDatabase(t *testing.T) {
 	t.Helper()
+	service.LimitStore = ratelimit.NewMemoryStore()
+	clearTable(t, "corrections")
+	clearTable(t, "audit_log")
+	clearTable(t, "practice_results")
+	clearTable(t, "drills")
+	clearTable(t, "reviews")
+	clearTable(t, "review_cards")
+	clearTable(t, "deleted_verb_forms")
 	clearTable(t, "verb_forms")
+	clearTable(t, "infinitives")
 	clearTable(t, "words")
 	clearTable(t, "languages")
 	clearTable(t, "users")
@@ -83,9 +95,10 @@ func clearDatabase(t *testing.T) {
 
 // createCompleteVerb inserts the complete structure of a made up verb.
 // That structure includes the infinitive and number/singularity of present
-// and past tenses.
-// returns (infinitive, id of infinitive)
-func createCompleteVerb(t *testing.T) (string, int) {
+// and past indicative tenses, along with the singular imperative, the past
+// participle, and the auxiliary.
+// returns (id of the language, infinitive)
+func createCompleteVerb(t *testing.T) (int, string) {
 	t.Helper()
 	var langId int
 	db.QueryRow(`
@@ -94,24 +107,151 @@ func createCompleteVerb(t *testing.T) (string, int) {
 	).Scan(&langId)
 
 	var infId int
-	db.QueryRow(`INSERT INTO words (word) VALUES ('krijgen') RETURNING id`).Scan(&infId)
+	db.QueryRow(`
+		INSERT INTO words (lang_id, word)
+		VALUES ($1, 'krijgen') RETURNING id`,
+		langId,
+	).Scan(&infId)
 
 	db.Exec(`
-		INSERT INTO words (word) VALUES
-		('krijg'), ('krijgt'), ('kreeg'), ('kreegt'), ('kregen')
-	`)
+		INSERT INTO words (lang_id, word)
+		SELECT $1, word FROM (VALUES
+		  ('krijg'), ('krijgt'), ('kreeg'), ('kreegt'), ('kregen'),
+		  ('gekregen'), ('hebben')) AS forms (word)`,
+		langId,
+	)
+	db.Exec(`
+		INSERT INTO infinitives (lang_id, word_id, aux_id, past_ptc_id)
+		VALUES
+		($1, $2,
+		(SELECT id FROM words WHERE word = 'hebben'),
+		(SELECT id FROM words WHERE word = 'gekregen'))`,
+		langId, infId,
+	)
 	db.Exec(`
-		INSERT INTO verb_forms (lang_id, word_id, inf_id, tense_id, person, num)
+		INSERT INTO verb_forms
+		(lang_id, word_id, inf_id, tense_id, mood_id, person, num)
 		VALUES
-		($1, (SELECT id FROM words WHERE word = 'krijg'), $2, 1, 2, 1),
-		($1, (SELECT id FROM words WHERE word = 'krijgt'), $2, 1, 12, 1),
-		($1, $2, $2, 1, NULL, 2),
-		($1, (SELECT id FROM words WHERE word = 'kreeg'), $2, 2, 14, 1),
-		($1, (SELECT id FROM words WHERE word = 'kreegt'), $2, 2, 4, 1),
-		($1, (SELECT id FROM words WHERE word = 'kregen'), $2, 2, NULL, 2)`,
+		($1, (SELECT id FROM words WHERE word = 'krijg'), $2, 1, 1, 2, 1),
+		($1, (SELECT id FROM words WHERE word = 'krijgt'), $2, 1, 1, 12, 1),
+		($1, $2, $2, 1, 1, NULL, 2),
+		($1, (SELECT id FROM words WHERE word = 'kreeg'), $2, 2, 1, 14, 1),
+		($1, (SELECT id FROM words WHERE word = 'kreegt'), $2, 2, 1, 4, 1),
+		($1, (SELECT id FROM words WHERE word = 'kregen'), $2, 2, 1, NULL, 2),
+		($1, (SELECT id FROM words WHERE word = 'krijg'), $2, 1, 3, 4, 1)`,
 		langId, infId,
 	)
-	return "krijgen", infId
+	return langId, "krijgen"
+}
+
+// createWeakVerb inserts the present forms of the weak verb 'werken' into
+// the language created by createCompleteVerb. Its past forms are left out so
+// that rules must fill them.
+func createWeakVerb(t *testing.T) {
+	t.Helper()
+	_, err := db.Exec(`
+		INSERT INTO words (lang_id, word)
+		SELECT languages.id, word
+		FROM languages, (VALUES ('werken'), ('werk'), ('werkt')) AS forms (word)
+		WHERE languages.name = 'dutch'`,
+	)
+	checkError(t, err)
+	_, err = db.Exec(`
+		INSERT INTO infinitives (lang_id, word_id, class_id)
+		SELECT languages.id, words.id, verb_classes.id
+		FROM languages, words, verb_classes
+		WHERE languages.name = 'dutch'
+		AND   words.word = 'werken'
+		AND   verb_classes.class = 'weak'`,
+	)
+	checkError(t, err)
+	_, err = db.Exec(`
+		INSERT INTO verb_forms
+		(lang_id, word_id, inf_id, tense_id, mood_id, person, num)
+		SELECT languages.id, form.id, inf.id, 1, 1, forms.person, forms.num
+		FROM languages, words form, words inf,
+		  (VALUES ('werk', 2, 1), ('werkt', 12, 1), ('werken', NULL, 2))
+		  AS forms (form, person, num)
+		WHERE languages.name = 'dutch'
+		AND   form.word = forms.form
+		AND   inf.word  = 'werken'`,
+	)
+	checkError(t, err)
+}
+
+// createGermanVerb inserts the present plural forms of 'machen' and of its
+// auxiliaries 'haben' and 'werden', whose second person differs from the
+// others, along with the past participle of 'machen'.
+// returns (id of the language, infinitive)
+func createGermanVerb(t *testing.T) (int, string) {
+	t.Helper()
+	var langId int
+	err := db.QueryRow(`
+		INSERT INTO languages (name)
+		VALUES ('german') RETURNING id`,
+	).Scan(&langId)
+	checkError(t, err)
+
+	_, err = db.Exec(`
+		INSERT INTO words (lang_id, word)
+		SELECT $1, word FROM (VALUES
+		  ('machen'), ('macht'), ('gemacht'), ('haben'), ('habt'),
+		  ('werden'), ('werdet')) AS forms (word)`,
+		langId,
+	)
+	checkError(t, err)
+	_, err = db.Exec(`
+		INSERT INTO infinitives (lang_id, word_id, aux_id, past_ptc_id)
+		SELECT $1, inf.id, aux.id, ptc.id
+		FROM words inf, words aux, words ptc
+		WHERE inf.word = 'machen' AND aux.word = 'haben'
+		AND   ptc.word = 'gemacht'`,
+		langId,
+	)
+	checkError(t, err)
+	_, err = db.Exec(`
+		INSERT INTO verb_forms
+		(lang_id, word_id, inf_id, tense_id, mood_id, person, num)
+		SELECT $1, form.id, inf.id, 1, 1, forms.person, 2
+		FROM words form, words inf,
+		  (VALUES ('machen', 'machen', 10), ('macht', 'machen', 4),
+		          ('haben', 'haben', 10), ('habt', 'haben', 4),
+		          ('werden', 'werden', 10), ('werdet', 'werden', 4))
+		  AS forms (form, inf, person)
+		WHERE form.word = forms.form
+		AND   inf.word  = forms.inf`,
+		langId,
+	)
+	checkError(t, err)
+	return langId, "machen"
+}
+
+// createAuxiliaries inserts the first person singular forms of 'hebben' and
+// 'zullen' into the language created by createCompleteVerb.
+func createAuxiliaries(t *testing.T) {
+	t.Helper()
+	db.Exec(`
+		INSERT INTO words (lang_id, word)
+		SELECT languages.id, word
+		FROM languages, (VALUES
+		  ('hebben'), ('heb'), ('had'), ('zullen'), ('zal'), ('zou'))
+		  AS forms (word)
+		WHERE languages.name = 'dutch'
+		ON CONFLICT DO NOTHING
+	`)
+	_, err := db.Exec(`
+		INSERT INTO verb_forms
+		(lang_id, word_id, inf_id, tense_id, mood_id, person, num)
+		SELECT languages.id, form.id, inf.id, tense, 1, 2, 1
+		FROM languages, words form, words inf,
+		  (VALUES ('heb', 'hebben', 1), ('had', 'hebben', 2),
+		          ('zal', 'zullen', 1), ('zou', 'zullen', 2))
+		  AS forms (form, inf, tense)
+		WHERE languages.name = 'dutch'
+		AND   form.word = forms.form
+		AND   inf.word  = forms.inf`,
+	)
+	checkError(t, err)
 }
 
 // createVerbForm inserts a value into the test database's verb_forms table.
@@ -119,13 +259,16 @@ func createCompleteVerb(t *testing.T) (string, int) {
 func createVerbForm(t *testing.T) (int, int, int) {
 	t.Helper()
 
-	_, wordId := addWord(t)
 	langId, _ := addLanguage(t)
+	_, wordId := addWord(t, langId)
 
 	var formId int
 	err := db.QueryRow(`
-		INSERT INTO verb_forms (lang_id, word_id, inf_id, tense_id, num)
-		VALUES ($1, $2, $3, (SELECT id FROM tenses WHERE tense = 'past'), 2)
+		INSERT INTO verb_forms (lang_id, word_id, inf_id, tense_id, mood_id, num)
+		VALUES ($1, $2, $3,
+		  (SELECT id FROM tenses WHERE tense = 'past'),
+		  (SELECT id FROM moods WHERE mood = 'indicative'),
+		  2)
 		RETURNING id`,
 		langId, wordId, wordId,
 	).Scan(&formId)
@@ -134,24 +277,29 @@ func createVerbForm(t *testing.T) (int, int, int) {
 	return langId, wordId, formId
 }
 
-// addWord inserts a word into the test database's words table.
+// addWord inserts a word of a language into the test database's words table.
 // returns (the inserted word, word's id)
-func addWord(t *testing.T) (string, int) {
+func addWord(t *testing.T, langId int) (string, int) {
 	t.Helper()
 
 	word := uuid.NewV4().String()
 	var wordId int
 
 	err := db.QueryRow(`
-		INSERT INTO words (word)
-		VALUES ($1) RETURNING id`,
-		word,
+		INSERT INTO words (lang_id, word)
+		VALUES ($1, $2) RETURNING id`,
+		langId, word,
 	).Scan(&wordId)
 
 	checkError(t, err)
 	return word, wordId
 }
 
+// wordPath returns the path of a word resource in a language.
+func wordPath(langId int, word string) string {
+	return "/api/v1/languages/" + strconv.Itoa(langId) + "/words/" + word
+}
+
 // addLanguage inserts a language into the test database's languages table.
 // returns (the language's id, the language)
 func addLanguage(t *testing.T) (id int, name string) {
@@ -188,6 +336,61 @@ func requestAccount(t *testing.T, user, pass string) string {
 	return respBody["token"]
 }
 
+// requestToken signs in as an existing user.
+// returns the jwt token
+func requestToken(t *testing.T, user, pass string) string {
+	t.Helper()
+
+	req, err := http.NewRequest("GET", "/api/v1/tokens", nil)
+	checkError(t, err)
+
+	cred := base64.StdEncoding.EncodeToString([]byte(user + ":" + pass))
+	req.Header.Set("Authorization", "Basic "+cred)
+	resp := sendRequest(req)
+	checkCode(t, http.StatusOK, resp.Code)
+
+	var respBody map[string]string
+	json.Unmarshal(resp.Body.Bytes(), &respBody)
+
+	return respBody["token"]
+}
+
+// createLearner creates an account that targets a language.
+// returns (user id, jwt token)
+func createLearner(t *testing.T, langId int) (string, string) {
+	t.Helper()
+	name := uuid.NewV4().String()
+	token := requestAccount(t, name, "pass")
+
+	var userId string
+	err := db.QueryRow(`
+		UPDATE users SET target_language_id = $2
+		WHERE name = $1
+		RETURNING id`,
+		name, langId,
+	).Scan(&userId)
+	checkError(t, err)
+	return userId, token
+}
+
+// createDrill inserts a drill that asks a user for the 3rd person singular
+// past of the verb created by createCompleteVerb.
+// returns the id of the drill
+func createDrill(t *testing.T, userId string) int {
+	t.Helper()
+	var id int
+	err := db.QueryRow(`
+		INSERT INTO drills (user_id, lang_id, inf_id, tense_id, mood_id,
+		                    person, num)
+		SELECT $1, lang_id, id, 2, 1, 8, 1
+		FROM words WHERE word = 'krijgen'
+		RETURNING id`,
+		userId,
+	).Scan(&id)
+	checkError(t, err)
+	return id
+}
+
 // makeAdmin gives a user the admin role.
 func makeAdmin(t *testing.T, userId string) {
 	t.Helper()
@@ -200,6 +403,24 @@ func makeAdmin(t *testing.T, userId string) {
 	checkError(t, err)
 }
 
+// getWordId returns the id of a word in the test database.
+func getWordId(t *testing.T, text string) int {
+	t.Helper()
+	var id int
+	err := db.QueryRow(`SELECT id FROM words WHERE word = $1`, text).Scan(&id)
+	checkError(t, err)
+	return id
+}
+
+// createAdmin creates a user with the admin role and signs them in.
+// returns (user id, jwt token)
+func createAdmin(t *testing.T) (string, string) {
+	t.Helper()
+	userId, user, pass := createUser(t)
+	makeAdmin(t, userId)
+	return userId, requestToken(t, user, pass)
+}
+
 // createUser inserts a new user into the test database.
 // returns (user id, username, password)
 func createUser(t *testing.T) (string, string, string) {
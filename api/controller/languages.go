@@ -28,13 +28,26 @@ func (lang *Languages) Routes() []Route {
 			Handler:     lang.getLanguage,
 			IsProtected: false,
 		},
+		{ // GET /v1/languages/{id:[0-9]+}/rules/accuracy
+			Version:     "v1",
+			Path:        "/languages/{id:[0-9]+}/rules/accuracy",
+			Method:      "GET",
+			Handler:     lang.getRuleAccuracy,
+			IsProtected: true,
+		},
 	}
 }
 
-// GET /api/v1/languages
+// GET /api/v1/languages?limit=20&cursor=MjA&sort=name
 func (lang *Languages) getLanguages(w http.ResponseWriter, r *http.Request) {
-	languages, err := lang.db.GetLanguages()
-	respondWithAggregate(w, languages, len(languages), err, "languages")
+	opts, err := parseListOptions(r, model.LanguageSortFields)
+	if err != nil {
+		makeErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	languages, err := lang.db.GetLanguages(peek(opts))
+	respondWithPage(w, r, languages, opts, err, "languages")
 }
 
 // GET /api/v1/languages/{id:[0-9]+}
@@ -51,4 +64,21 @@ func (lang *Languages) getLanguage(w http.ResponseWriter, r *http.Request) {
 	} else {
 		makeJsonResponse(w, http.StatusOK, language)
 	}
-}
\ No newline at end of file
+}
+
+// GET /api/v1/languages/{id:[0-9]+}/rules/accuracy
+func (lang *Languages) getRuleAccuracy(w http.ResponseWriter, r *http.Request) {
+	languageId, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		makeErrorResponse(w, http.StatusBadRequest, "invalid language id")
+		return
+	}
+
+	accuracy, err := lang.db.MeasureRules(languageId)
+	if err != nil {
+		makeErrorResponse(w, http.StatusNotFound,
+			"language not found or has no conjugation rules")
+	} else {
+		makeJsonResponse(w, http.StatusOK, accuracy)
+	}
+}
@@ -0,0 +1,110 @@
+package controller
+
+import (
+	"encoding/base64"
+	"errors"
+	"mutably/api/model"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Collections are returned in pages of this many items unless the request
+// asks for another amount.
+const defaultPageSize, maxPageSize = 100, 1000
+
+// parseListOptions reads the limit, cursor, sort, and language query
+// parameters of a request for a collection. Sort must name one of fields,
+// optionally prefixed with '-' to reverse the order.
+func parseListOptions(r *http.Request,
+	fields model.SortFields) (model.ListOptions, error) {
+	params := r.URL.Query()
+	var opts model.ListOptions
+
+	var err error
+	opts.Limit, err = intParam(params.Get("limit"), defaultPageSize)
+	if err != nil || opts.Limit < 1 || opts.Limit > maxPageSize {
+		return opts, errors.New("limit must be between 1 and " +
+			strconv.Itoa(maxPageSize))
+	}
+
+	if cursor := params.Get("cursor"); cursor != "" {
+		opts.Offset, err = decodeCursor(cursor)
+		if err != nil {
+			return opts, errors.New("invalid cursor")
+		}
+	}
+
+	if sort := params.Get("sort"); sort != "" {
+		opts.Descending = strings.HasPrefix(sort, "-")
+		opts.Sort = strings.TrimPrefix(sort, "-")
+		if _, ok := fields[opts.Sort]; !ok {
+			return opts, errors.New("cannot sort by " + opts.Sort)
+		}
+	}
+
+	opts.LanguageId, err = intParam(params.Get("language"), 0)
+	if err != nil || opts.LanguageId < 0 {
+		return opts, errors.New("invalid language id")
+	}
+	return opts, nil
+}
+
+// peek returns opts with room for one more item than the page holds. If
+// that item is found, there is a next page.
+func peek(opts model.ListOptions) model.ListOptions {
+	opts.Limit++
+	return opts
+}
+
+// respondWithPage responds like respondWithAggregate with a page of objects,
+// which must be a slice that was listed with peek(opts). If it holds the
+// extra item, the item is dropped and the next page is linked with the Link
+// and X-Next-Cursor headers.
+func respondWithPage(w http.ResponseWriter, r *http.Request,
+	objects interface{}, opts model.ListOptions, err error, resource string) {
+	items := reflect.ValueOf(objects)
+	length := items.Len()
+	if length > opts.Limit {
+		objects, length = items.Slice(0, opts.Limit).Interface(), opts.Limit
+		if err == nil {
+			linkNextPage(w, r, opts)
+		}
+	}
+	respondWithAggregate(w, objects, length, err, resource)
+}
+
+// linkNextPage sets the Link and X-Next-Cursor headers to the page after
+// the one opts describes.
+func linkNextPage(w http.ResponseWriter, r *http.Request,
+	opts model.ListOptions) {
+	cursor := encodeCursor(opts.Offset + opts.Limit)
+
+	next := *r.URL
+	query := next.Query()
+	query.Set("cursor", cursor)
+	next.RawQuery = query.Encode()
+
+	w.Header().Set("X-Next-Cursor", cursor)
+	w.Header().Set("Link", "<"+next.RequestURI()+`>; rel="next"`)
+}
+
+// Cursors are opaque to clients so that the way pages are found can change
+// without breaking them.
+
+func encodeCursor(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(offset)))
+}
+
+func decodeCursor(cursor string) (int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, err
+	}
+	offset, err := strconv.Atoi(string(b))
+	if err == nil && offset < 0 {
+		err = errors.New("negative offset")
+	}
+	return offset, err
+}
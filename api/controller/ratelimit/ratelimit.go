@@ -0,0 +1,100 @@
+// Package ratelimit decides whether clients have made too many requests. It
+// implements token buckets: each client has a bucket of tokens that its
+// requests spend and that refills at a steady rate.
+//
+// A bucket is stored as the time at which it will be full again, so stores
+// only need to keep one timestamp per client.
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// Limit allows Requests requests at once and refills them evenly over Per.
+// The zero Limit allows any number of requests.
+type Limit struct {
+	// The number of tokens in a full bucket
+	Requests int
+
+	// How long an empty bucket takes to fill
+	Per time.Duration
+}
+
+// IsZero reports whether l allows any number of requests.
+func (l Limit) IsZero() bool {
+	return l.Requests <= 0 || l.Per <= 0
+}
+
+// Spend takes one token at now from a bucket that is full at full. The zero
+// time is a full bucket. If a token is left, Spend returns when the bucket
+// will be full afterwards. If not, it returns false and how long until the
+// bucket has a token.
+func Spend(full time.Time, limit Limit, now time.Time) (time.Time, bool,
+	time.Duration) {
+	if limit.IsZero() {
+		return full, true, 0
+	}
+	if full.Before(now) {
+		full = now
+	}
+
+	// Each token refills in interval, so a bucket that is full more than
+	// Per - interval from now has no tokens left.
+	interval := limit.Per / time.Duration(limit.Requests)
+	if wait := full.Sub(now) - (limit.Per - interval); wait > 0 {
+		return full, false, wait
+	}
+	return full.Add(interval), true, 0
+}
+
+// Store keeps the buckets of clients. Implementations must be safe for
+// concurrent use.
+type Store interface {
+	// Take spends a token at now from the bucket of key. If there is none,
+	// it returns false and how long until there is.
+	Take(key string, limit Limit, now time.Time) (bool, time.Duration, error)
+}
+
+// How often a MemoryStore forgets buckets that are full
+const sweepInterval = time.Minute
+
+// MemoryStore is a Store that keeps buckets in memory. It suits a single
+// API instance; instances that share clients need a shared Store.
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]time.Time
+	swept   time.Time
+}
+
+// NewMemoryStore creates and returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]time.Time)}
+}
+
+func (s *MemoryStore) Take(key string, limit Limit,
+	now time.Time) (bool, time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sweep(now)
+
+	full, ok, wait := Spend(s.buckets[key], limit, now)
+	if ok {
+		s.buckets[key] = full
+	}
+	return ok, wait, nil
+}
+
+// sweep forgets the buckets that are full at now. They are no different
+// from the new buckets that unknown keys get.
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.swept) < sweepInterval {
+		return
+	}
+	s.swept = now
+	for key, full := range s.buckets {
+		if !full.After(now) {
+			delete(s.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package ratelimit_test
+
+import (
+	"mutably/api/controller/ratelimit"
+	"testing"
+	"time"
+)
+
+var start = time.Date(2018, 1, 1, 9, 0, 0, 0, time.UTC)
+
+// A bucket should allow a burst of Requests and then one request for each
+// interval that passes.
+func TestMemoryStore_Take(t *testing.T) {
+	store := ratelimit.NewMemoryStore()
+	limit := ratelimit.Limit{Requests: 3, Per: 3 * time.Second}
+
+	for i := 0; i < 3; i++ {
+		if ok, _, _ := store.Take("client", limit, start); !ok {
+			t.Fatal("Expected a burst of 3 requests, denied request", i+1)
+		}
+	}
+	ok, wait, _ := store.Take("client", limit, start)
+	if ok || wait != time.Second {
+		t.Error("Expected to wait 1s for the next request, got", ok, wait)
+	}
+
+	if ok, _, _ := store.Take("other", limit, start); !ok {
+		t.Error("Expected clients to have separate buckets")
+	}
+
+	now := start.Add(time.Second)
+	if ok, _, _ := store.Take("client", limit, now); !ok {
+		t.Error("Expected a token to refill after 1s")
+	}
+	if ok, _, _ := store.Take("client", limit, now); ok {
+		t.Error("Expected only one token to refill after 1s")
+	}
+}
+
+// Buckets that were left alone long enough should be full again.
+func TestMemoryStore_refill(t *testing.T) {
+	store := ratelimit.NewMemoryStore()
+	limit := ratelimit.Limit{Requests: 2, Per: time.Minute}
+	store.Take("client", limit, start)
+	store.Take("client", limit, start)
+
+	now := start.Add(2 * time.Minute)
+	for i := 0; i < 2; i++ {
+		if ok, _, _ := store.Take("client", limit, now); !ok {
+			t.Fatal("Expected a full bucket, denied request", i+1)
+		}
+	}
+}
+
+// The zero Limit should allow any number of requests.
+func TestSpend_zero(t *testing.T) {
+	var limit ratelimit.Limit
+	for i := 0; i < 100; i++ {
+		_, ok, _ := ratelimit.Spend(time.Time{}, limit, start)
+		if !ok {
+			t.Fatal("Expected the zero limit to allow every request")
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package controller
+
+import (
+	"mutably/api/model"
+	"net/http"
+)
+
+// Roles is a Controller for the /roles resource.
+type Roles struct {
+	db model.Database
+}
+
+func (ro *Roles) Routes() []Route {
+	return []Route{
+		{ // GET /api/v1/roles
+			Version: "v1",
+			Path:    "/roles",
+			Method:  "GET",
+			Handler: ro.getRoles,
+			Role:    model.RoleAdmin,
+		},
+	}
+}
+
+// GET /api/v1/roles
+func (ro *Roles) getRoles(w http.ResponseWriter, r *http.Request) {
+	roles, err := ro.db.GetRoles()
+	respondWithAggregate(w, roles, len(roles), err, "roles")
+}
@@ -1,12 +1,18 @@
 package controller
 
 import (
+	"database/sql"
 	"encoding/json"
 	"errors"
 	"log"
+	"math"
+	"mutably/api/controller/ratelimit"
 	"mutably/api/model"
 	"net"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/rs/cors"
@@ -23,6 +29,18 @@ type Service struct {
 	versionRouters map[string]*mux.Router
 	port           string
 	auth           *model.AuthLayer
+
+	// Keeps the request counts of clients for routes with a Limit. It is
+	// in memory by default.
+	LimitStore ratelimit.Store
+
+	// The header that a reverse proxy puts the address of clients in (e.g.,
+	// X-Forwarded-For or X-Real-IP). Clients without a token are limited by
+	// that address. If it is empty, the address of the connection is used,
+	// which is the proxy's own when there is one. Clients can send any
+	// header, so it should only be set if the proxy overwrites or appends
+	// to it.
+	ClientIPHeader string
 }
 
 // NewService creates and returns a Service instance.
@@ -42,17 +60,23 @@ func NewService(database model.Database, port string) (*Service, error) {
 		Router:         mux.NewRouter(),
 		versionRouters: make(map[string]*mux.Router),
 		port:           port,
-		auth:           model.NewAuthLayer(),
+		auth:           model.NewAuthLayer(database),
+		LimitStore:     ratelimit.NewMemoryStore(),
 	}
 
 	service.AddController(&Users{db: database, auth: service.auth})
 	service.AddController(&Languages{db: database})
-	service.AddController(&Words{db: database})
-	// Todo: Probably create a Controller if another endpoint is created for
-	//       this resource.
-	service.versionRouters["v1"].HandleFunc("/tokens",
-		service.getToken).Methods("GET")
-
+	service.AddController(&Words{db: database, auth: service.auth})
+	service.AddController(&VerbForms{db: database, auth: service.auth})
+	service.AddController(&Corrections{db: database, auth: service.auth})
+	service.AddController(&Drills{db: database, auth: service.auth})
+	service.AddController(&Cards{db: database, auth: service.auth})
+	tokens := &Tokens{db: database, auth: service.auth}
+	service.AddController(tokens)
+	service.AddController(&Roles{db: database})
+
+	service.Router.HandleFunc("/.well-known/jwks.json",
+		tokens.getPublicKeys).Methods("GET")
 	return service, nil
 }
 
@@ -65,13 +89,68 @@ func (service *Service) AddController(controllers Controller) {
 			service.versionRouters[route.Version] = router
 		}
 
-		if route.IsProtected {
-			router.HandleFunc(route.Path,
-				service.auth.Authenticate(route.Handler)).Methods(route.Method)
-		} else {
-			router.HandleFunc(route.Path, route.Handler).Methods(route.Method)
+		handler := route.Handler
+		if route.Role != "" {
+			handler = service.auth.RequireRole(route.Role, handler)
+		}
+		if route.IsProtected || route.Role != "" {
+			handler = service.auth.Authenticate(handler)
+		}
+		if !route.Limit.IsZero() {
+			handler = service.limit(route, handler)
+		}
+		router.HandleFunc(route.Path, handler).Methods(route.Method)
+	}
+}
+
+// limit returns a handler that responds with 429 Too Many Requests to
+// clients that exceed the limit of a route. Requests are allowed if the
+// counts can't be read, so that a broken store doesn't stop the API.
+func (service *Service) limit(route Route,
+	handler http.HandlerFunc) http.HandlerFunc {
+	name := route.Method + " /api/" + route.Version + route.Path
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := name + " " + service.clientId(r)
+		ok, wait, err := service.LimitStore.Take(key, route.Limit, time.Now())
+		if err != nil {
+			log.Println(err)
+		} else if !ok {
+			seconds := int(math.Ceil(wait.Seconds()))
+			w.Header().Set("Retry-After", strconv.Itoa(seconds))
+			makeErrorResponse(w, http.StatusTooManyRequests,
+				"too many requests; try again in "+strconv.Itoa(seconds)+"s")
+			return
 		}
+		handler(w, r)
+	}
+}
+
+// clientId identifies the client of a request by the user id of its token
+// or, if it has no valid token, by its IP address. Revoked tokens count as
+// invalid, so that old tokens can't be used for fresh buckets.
+func (service *Service) clientId(r *http.Request) string {
+	if id, err := service.auth.UserId(r); err == nil && id != "" {
+		return "user:" + id
 	}
+	return "ip:" + service.clientIP(r)
+}
+
+// clientIP returns the IP address of the client of a request. The address
+// in ClientIPHeader is preferred. Proxies append the address they received
+// a request from to X-Forwarded-For, so the last one in a list is used.
+func (service *Service) clientIP(r *http.Request) string {
+	if service.ClientIPHeader != "" {
+		addresses := strings.Split(r.Header.Get(service.ClientIPHeader), ",")
+		if last := strings.TrimSpace(addresses[len(addresses)-1]); last != "" {
+			return last
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return host
 }
 
 // Start makes service begin listening for connections on the specified port.
@@ -120,20 +199,30 @@ func respondWithAggregate(w http.ResponseWriter, objects interface{},
 	}
 }
 
-// GET /api/v1/tokens
-func (service *Service) getToken(w http.ResponseWriter, r *http.Request) {
-	username, password, err := service.auth.GetCredentials(r)
-	if err != nil {
+// respondWithEdit reports the result of an edit to conjugation data. On
+// success, result is sent with code; a nil result sends no body.
+func respondWithEdit(w http.ResponseWriter, code int, result interface{},
+	err error, resource string) {
+	switch err {
+	case nil:
+		if result == nil {
+			w.WriteHeader(code)
+		} else {
+			makeJsonResponse(w, code, result)
+		}
+	case sql.ErrNoRows:
+		makeErrorResponse(w, http.StatusNotFound, resource+" not found")
+	case model.ErrWordExists, model.ErrFormExists, model.ErrWordInUse,
+		model.ErrAlreadyReviewed, model.ErrStaleCorrection:
+		makeErrorResponse(w, http.StatusConflict, err.Error())
+	case model.ErrUnknownLanguage, model.ErrUnknownCell, model.ErrInvalidForm,
+		model.ErrUnrelatedForm:
 		makeErrorResponse(w, http.StatusBadRequest, err.Error())
-		return
-	}
-
-	userId := service.db.GetUserId(username, password)
-	if userId == "" {
-		makeErrorResponse(w, http.StatusUnauthorized,
-			"invalid user credentials")
-	} else {
-		w.WriteHeader(http.StatusOK)
-		service.auth.GenerateTokenWithClaim(w, map[string]interface{}{"id": userId})
+	case model.ErrImportRunning:
+		makeErrorResponse(w, http.StatusServiceUnavailable, err.Error())
+	default:
+		log.Println(err)
+		makeErrorResponse(w, http.StatusInternalServerError,
+			"could not edit "+resource)
 	}
 }
@@ -1,6 +1,7 @@
 package controller_test
 
 import (
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
@@ -8,6 +9,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"strconv"
+	"strings"
 	"testing"
 
 	_ "github.com/lib/pq"
@@ -123,6 +125,60 @@ func TestGetWords_v1_notempty(t *testing.T) {
 	}
 }
 
+// APIv1 should split words into sorted pages that link to the next one.
+func TestGetWords_v1_pages(t *testing.T) {
+	clearDatabase(t)
+	langId, _ := addLanguage(t)
+	_, err := db.Exec(`
+		INSERT INTO words (lang_id, word) VALUES
+		($1, 'a'), ($1, 'b'), ($1, 'c'), ($1, 'd'), ($1, 'e')`,
+		langId,
+	)
+	checkError(t, err)
+
+	var texts []string
+	path := "/api/v1/words?limit=2&sort=-text"
+	for pages := 0; path != ""; pages++ {
+		if pages == 3 {
+			t.Fatal("Expected the last page to have no next link")
+		}
+		req, _ := http.NewRequest("GET", path, nil)
+		resp := sendRequest(req)
+		checkCode(t, http.StatusOK, resp.Code)
+
+		var words []model.Word
+		json.Unmarshal(resp.Body.Bytes(), &words)
+		for _, word := range words {
+			texts = append(texts, word.Text)
+		}
+
+		path = ""
+		if cursor := resp.Header().Get("X-Next-Cursor"); cursor != "" {
+			path = "/api/v1/words?limit=2&sort=-text&cursor=" + cursor
+			if !strings.Contains(resp.Header().Get("Link"), `rel="next"`) {
+				t.Error("Expected a Link header to the next page")
+			}
+		}
+	}
+
+	if strings.Join(texts, "") != "edcba" {
+		t.Error("Expected words in reverse order, got", texts)
+	}
+}
+
+// APIv1 should reject list requests with options it can't use.
+func TestGetWords_v1_invalidOptions(t *testing.T) {
+	clearDatabase(t)
+	createVerbForm(t)
+
+	for _, query := range []string{"limit=0", "limit=1001", "cursor=%3F",
+		"sort=password", "language=x", "q=a&sort=text"} {
+		req, _ := http.NewRequest("GET", "/api/v1/words?"+query, nil)
+		resp := sendRequest(req)
+		checkCode(t, http.StatusBadRequest, resp.Code)
+	}
+}
+
 // APIv1 should return a 404 response code if a requested word does not exist.
 func TestGetWord_v1_empty(t *testing.T) {
 	clearDatabase(t)
@@ -152,6 +208,227 @@ func TestGetWord_v1_notempty(t *testing.T) {
 	}
 }
 
+// APIv1 should rank words that match a search: exact matches (ignoring
+// accents) first, then prefixes, then other substrings.
+func TestSearchWords_v1(t *testing.T) {
+	clearDatabase(t)
+	langId, _ := addLanguage(t)
+	otherLangId, _ := addLanguage(t)
+	_, err := db.Exec(`
+		INSERT INTO words (lang_id, word) VALUES
+		($1, 'geen'), ($1, 'eens'), ($1, 'één'), ($1, 'krijgen'), ($2, 'een')`,
+		langId, otherLangId,
+	)
+	checkError(t, err)
+
+	req, _ := http.NewRequest("GET",
+		"/api/v1/words?q=een&language="+strconv.Itoa(langId), nil)
+	resp := sendRequest(req)
+	checkCode(t, http.StatusOK, resp.Code)
+
+	var words []model.Word
+	json.Unmarshal(resp.Body.Bytes(), &words)
+
+	expected := []string{"één", "eens", "geen"}
+	if len(words) != len(expected) {
+		t.Fatal("Expected", expected, "got", words)
+	}
+	for i, word := range words {
+		if word.Text != expected[i] {
+			t.Errorf("Expected result %d to be '%s', got '%s'", i, expected[i],
+				word.Text)
+		}
+	}
+}
+
+// APIv1 should reject searches with a bad limit and treat wildcards in the
+// query as plain text.
+func TestSearchWords_v1_invalid(t *testing.T) {
+	clearDatabase(t)
+	createVerbForm(t)
+
+	req, _ := http.NewRequest("GET", "/api/v1/words?q=a&limit=0", nil)
+	resp := sendRequest(req)
+	checkCode(t, http.StatusBadRequest, resp.Code)
+
+	req, _ = http.NewRequest("GET", "/api/v1/words?q=%25", nil)
+	resp = sendRequest(req)
+	checkCode(t, http.StatusNotFound, resp.Code)
+}
+
+// APIv1 should suggest the known words nearest to a misspelled one, both
+// when a lookup fails and when asked directly.
+func TestGetSuggestions_v1(t *testing.T) {
+	clearDatabase(t)
+	langId, _ := createCompleteVerb(t)
+
+	req, _ := http.NewRequest("GET", wordPath(langId, "krijgn")+"/inflections",
+		nil)
+	resp := sendRequest(req)
+	checkCode(t, http.StatusNotFound, resp.Code)
+
+	var body struct {
+		Error       string
+		Suggestions []model.Suggestion
+	}
+	json.Unmarshal(resp.Body.Bytes(), &body)
+	if len(body.Suggestions) == 0 || body.Suggestions[0].Text != "krijgen" ||
+		body.Suggestions[0].Distance != 1 {
+		t.Error("Expected 'krijgen' to be suggested for 'krijgn'")
+	}
+
+	req, _ = http.NewRequest("GET", "/api/v1/words/kreegn/suggestions?language="+
+		strconv.Itoa(langId), nil)
+	resp = sendRequest(req)
+	checkCode(t, http.StatusOK, resp.Code)
+
+	var suggestions []model.Suggestion
+	json.Unmarshal(resp.Body.Bytes(), &suggestions)
+	if len(suggestions) == 0 || suggestions[0].Text != "kregen" &&
+		suggestions[0].Text != "kreeg" {
+		t.Error("Expected a form of 'krijgen' to be suggested for 'kreegn'")
+	}
+}
+
+// APIv1 should not suggest words from other languages.
+func TestGetSuggestions_v1_otherLanguage(t *testing.T) {
+	clearDatabase(t)
+	createCompleteVerb(t)
+	otherLangId, _ := addLanguage(t)
+
+	req, _ := http.NewRequest("GET", "/api/v1/words/krijgn/suggestions?language="+
+		strconv.Itoa(otherLangId), nil)
+	resp := sendRequest(req)
+	checkCode(t, http.StatusNotFound, resp.Code)
+}
+
+// APIv1 should list every reading of a verb form, one per person.
+func TestGetAnalyses_v1(t *testing.T) {
+	clearDatabase(t)
+	langId, _ := createCompleteVerb(t)
+
+	req, _ := http.NewRequest("GET", "/api/v1/words/kreeg/analyses?language="+
+		strconv.Itoa(langId), nil)
+	resp := sendRequest(req)
+	checkCode(t, http.StatusOK, resp.Code)
+
+	var analyses []model.Analysis
+	json.Unmarshal(resp.Body.Bytes(), &analyses)
+	if len(analyses) != 3 {
+		t.Fatal("Expected three readings of 'kreeg', got", analyses)
+	}
+	for i, reading := range analyses {
+		if reading.Infinitive != "krijgen" || reading.Tense != "past" ||
+			reading.Mood != "indicative" || reading.Number != "singular" ||
+			reading.Person != i+1 {
+			t.Error("Unexpected reading of 'kreeg':", reading)
+		}
+	}
+
+	req, _ = http.NewRequest("GET", "/api/v1/words/krijgen/analyses", nil)
+	resp = sendRequest(req)
+	checkCode(t, http.StatusOK, resp.Code)
+
+	analyses = nil
+	json.Unmarshal(resp.Body.Bytes(), &analyses)
+	if len(analyses) != 2 || analyses[0].Number != "plural" ||
+		analyses[1].NonFinite != "infinitive" {
+		t.Error("Expected 'krijgen' to be plural and an infinitive, got",
+			analyses)
+	}
+}
+
+// APIv1 should give the readings of a form that belongs to several verbs.
+func TestGetAnalyses_v1_ambiguous(t *testing.T) {
+	clearDatabase(t)
+	langId, _ := addLanguage(t)
+	_, err := db.Exec(`
+		INSERT INTO words (lang_id, word)
+		VALUES ($1, 'zijn'), ($1, 'wassen'), ($1, 'was')`,
+		langId,
+	)
+	checkError(t, err)
+	_, err = db.Exec(`
+		INSERT INTO verb_forms
+		(lang_id, word_id, inf_id, tense_id, mood_id, person, num)
+		SELECT $1, form.id, inf.id, tense, 1, person, 1
+		FROM words form, words inf,
+		  (VALUES ('zijn', 2, 10), ('wassen', 1, 2)) AS forms (inf, tense, person)
+		WHERE form.word = 'was' AND inf.word = forms.inf`,
+		langId,
+	)
+	checkError(t, err)
+
+	req, _ := http.NewRequest("GET", "/api/v1/words/was/analyses", nil)
+	resp := sendRequest(req)
+	checkCode(t, http.StatusOK, resp.Code)
+
+	infinitives := make(map[string]int)
+	var analyses []model.Analysis
+	json.Unmarshal(resp.Body.Bytes(), &analyses)
+	for _, reading := range analyses {
+		infinitives[reading.Infinitive]++
+	}
+	if infinitives["zijn"] != 2 || infinitives["wassen"] != 1 {
+		t.Error("Expected 'was' to be a form of 'zijn' and 'wassen', got",
+			analyses)
+	}
+
+	req, _ = http.NewRequest("GET", "/api/v1/words/wass/analyses", nil)
+	resp = sendRequest(req)
+	checkCode(t, http.StatusNotFound, resp.Code)
+}
+
+// APIv1 should return only the words of the requested language.
+func TestGetLanguageWords_v1(t *testing.T) {
+	clearDatabase(t)
+	langId, wordId, _ := createVerbForm(t)
+	createVerbForm(t)
+
+	req, _ := http.NewRequest("GET",
+		"/api/v1/languages/"+strconv.Itoa(langId)+"/words", nil)
+	resp := sendRequest(req)
+	checkCode(t, http.StatusOK, resp.Code)
+
+	var words []model.Word
+	json.Unmarshal(resp.Body.Bytes(), &words)
+	if len(words) != 1 || words[0].Id != wordId {
+		t.Error("Expected only the word of language", langId)
+	}
+}
+
+// APIv1 should find a word by its spelling within a language. The same
+// spelling in two languages should be two different words.
+func TestGetLanguageWord_v1(t *testing.T) {
+	clearDatabase(t)
+	langId1, _ := addLanguage(t)
+	langId2, _ := addLanguage(t)
+	_, wordId1 := addWord(t, langId1)
+
+	var text string
+	db.QueryRow(`SELECT word FROM words WHERE id = $1`, wordId1).Scan(&text)
+	var wordId2 int
+	db.QueryRow(`INSERT INTO words (lang_id, word) VALUES ($1, $2) RETURNING id`,
+		langId2, text).Scan(&wordId2)
+
+	for langId, wordId := range map[int]int{langId1: wordId1, langId2: wordId2} {
+		req, _ := http.NewRequest("GET", wordPath(langId, text), nil)
+		resp := sendRequest(req)
+		checkCode(t, http.StatusOK, resp.Code)
+
+		var word model.Word
+		json.Unmarshal(resp.Body.Bytes(), &word)
+		if word.Id != wordId || word.LanguageId != langId {
+			t.Errorf("Expected word %d of language %d, got %d of %d",
+				wordId, langId, word.Id, word.LanguageId)
+		}
+	}
+
+	req, _ := http.NewRequest("GET", wordPath(langId1, "missing"), nil)
+	resp := sendRequest(req)
+	checkCode(t, http.StatusNotFound, resp.Code)
+}
+
 // APIv1 should return a 401 response code if the client sends a request
 // and has no token.
 func TestGetUsers_v1_forbidden(t *testing.T) {
@@ -163,6 +440,61 @@ func TestGetUsers_v1_forbidden(t *testing.T) {
 	checkCode(t, http.StatusUnauthorized, resp.Code)
 }
 
+// APIv1 should only list users to admins.
+func TestGetUsers_v1_admin(t *testing.T) {
+	clearDatabase(t)
+	adminId, admin, adminPass := createUser(t)
+	makeAdmin(t, adminId)
+	_, user, pass := createUser(t)
+
+	getUsers := func(token string) int {
+		req, _ := http.NewRequest("GET", "/api/v1/users", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		return sendRequest(req).Code
+	}
+	checkCode(t, http.StatusOK, getUsers(requestToken(t, admin, adminPass)))
+	checkCode(t, http.StatusForbidden, getUsers(requestToken(t, user, pass)))
+}
+
+// APIv1 should let admins change roles, but never remove the last admin.
+func TestSetRole_v1(t *testing.T) {
+	clearDatabase(t)
+	adminId, admin, adminPass := createUser(t)
+	makeAdmin(t, adminId)
+	adminToken := requestToken(t, admin, adminPass)
+	userId, user, pass := createUser(t)
+	userToken := requestToken(t, user, pass)
+
+	setRole := func(token, id, role string) int {
+		req, _ := http.NewRequest("PUT", "/api/v1/users/"+id+"/role",
+			strings.NewReader(`{"role": "`+role+`"}`))
+		req.Header.Set("Authorization", "Bearer "+token)
+		return sendRequest(req).Code
+	}
+
+	checkCode(t, http.StatusForbidden, setRole(userToken, userId, "admin"))
+	checkCode(t, http.StatusBadRequest, setRole(adminToken, userId, "owner"))
+	checkCode(t, http.StatusConflict, setRole(adminToken, adminId, "user"))
+	checkCode(t, http.StatusOK, setRole(adminToken, userId, "admin"))
+
+	// The old token claims the old role, so it no longer works.
+	req, _ := http.NewRequest("GET", "/api/v1/roles", nil)
+	req.Header.Set("Authorization", "Bearer "+userToken)
+	checkCode(t, http.StatusUnauthorized, sendRequest(req).Code)
+
+	req, _ = http.NewRequest("GET", "/api/v1/roles", nil)
+	req.Header.Set("Authorization", "Bearer "+requestToken(t, user, pass))
+	resp := sendRequest(req)
+	checkCode(t, http.StatusOK, resp.Code)
+	var roles []string
+	json.Unmarshal(resp.Body.Bytes(), &roles)
+	if len(roles) != 2 || roles[0] != "admin" || roles[1] != "user" {
+		t.Error("Expected roles admin and user, got", roles)
+	}
+
+	checkCode(t, http.StatusOK, setRole(adminToken, adminId, "user"))
+}
+
 // APIv1 should return a 401 response code if the client sends a request
 // and has no token.
 func TestGetUser_v1_forbidden(t *testing.T) {
@@ -174,6 +506,88 @@ func TestGetUser_v1_forbidden(t *testing.T) {
 	checkCode(t, http.StatusUnauthorized, resp.Code)
 }
 
+// APIv1 should change only the fields of a user that a PATCH names.
+func TestUpdateUser_v1(t *testing.T) {
+	clearDatabase(t)
+	langId, _ := createCompleteVerb(t)
+	userId, token := createLearner(t, langId)
+	_, takenName, _ := createUser(t)
+
+	patch := func(body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("PATCH", "/api/v1/users/"+userId,
+			strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		return sendRequest(req)
+	}
+
+	resp := patch(`{"name": "anna"}`)
+	checkCode(t, http.StatusOK, resp.Code)
+	var user model.User
+	json.Unmarshal(resp.Body.Bytes(), &user)
+	if user.Name != "anna" || user.TargetLanguageId.Int64 != int64(langId) {
+		t.Error("Expected only the name to change, got", user)
+	}
+
+	resp = patch(`{"targetLanguageId": null}`)
+	checkCode(t, http.StatusOK, resp.Code)
+	user = model.User{}
+	json.Unmarshal(resp.Body.Bytes(), &user)
+	if user.Name != "anna" || user.TargetLanguageId.Valid {
+		t.Error("Expected the target language to be cleared, got", user)
+	}
+
+	checkCode(t, http.StatusBadRequest,
+		patch(`{"targetLanguageId": `+strconv.Itoa(langId+1)+`}`).Code)
+	checkCode(t, http.StatusBadRequest, patch(`{"name": "a:b"}`).Code)
+	checkCode(t, http.StatusConflict, patch(`{"name": "`+takenName+`"}`).Code)
+}
+
+// APIv1 should only change a password if given the current one.
+func TestChangePassword_v1(t *testing.T) {
+	clearDatabase(t)
+	userId, user, pass := createUser(t)
+	token := requestToken(t, user, pass)
+
+	change := func(oldPass, newPass string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("PUT", "/api/v1/users/"+userId+"/password",
+			strings.NewReader(`{"oldPassword": "`+oldPass+
+				`", "newPassword": "`+newPass+`"}`))
+		req.Header.Set("Authorization", "Bearer "+token)
+		return sendRequest(req)
+	}
+
+	checkCode(t, http.StatusForbidden, change("wrong", "new").Code)
+	checkCode(t, http.StatusNoContent, change(pass, "new").Code)
+
+	req, _ := http.NewRequest("GET", "/api/v1/tokens", nil)
+	cred := base64.StdEncoding.EncodeToString([]byte(user + ":" + pass))
+	req.Header.Set("Authorization", "Basic "+cred)
+	if sendRequest(req).Code == http.StatusOK {
+		t.Error("Expected the old password to stop working")
+	}
+	requestToken(t, user, "new")
+}
+
+// APIv1 should let users delete their own accounts but not others'. The
+// tokens of deleted users should stop working.
+func TestDeleteUser_v1(t *testing.T) {
+	clearDatabase(t)
+	userId, user, pass := createUser(t)
+	_, otherUser, otherPass := createUser(t)
+
+	deleteUser := func(token string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("DELETE", "/api/v1/users/"+userId, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		return sendRequest(req)
+	}
+
+	token := requestToken(t, user, pass)
+	checkCode(t, http.StatusUnauthorized,
+		deleteUser(requestToken(t, otherUser, otherPass)).Code)
+	checkCode(t, http.StatusNoContent, deleteUser(token).Code)
+	checkCode(t, http.StatusUnauthorized, deleteUser(token).Code)
+}
+
 // APIv1 should return a status created code if asked to create a user with
 // name that is not in the database.
 func TestCreateUser_v1_unique(t *testing.T) {
@@ -204,9 +618,10 @@ func TestCreateUser_v1_duplicate(t *testing.T) {
 // table of a verb that does not exist.
 func TestGetInflections_v1_missing(t *testing.T) {
 	clearDatabase(t)
+	langId, _ := addLanguage(t)
 
 	word := uuid.NewV4().String()
-	req, _ := http.NewRequest("GET", "/api/v1/words/"+word+"/inflections", nil)
+	req, _ := http.NewRequest("GET", wordPath(langId, word)+"/inflections", nil)
 	resp := sendRequest(req)
 	checkCode(t, http.StatusNotFound, resp.Code)
 }
@@ -215,16 +630,23 @@ func TestGetInflections_v1_missing(t *testing.T) {
 // The table is expected to look like the following:
 // {
 //	"infinitive": string,
-//  "present": {"first":[string...], "second":[string...], "third":[string...], "plural":[string...]},
-//  "past": {"first":[string...], "second":[string...], "third":[string...], "plural":[string...]}
+//  "auxiliary": string,
+//  "nonFinite": {"presentParticiple": string, "pastParticiple": string, "gerund": string},
+//  "indicative": {
+//    "present": {"first":[string...], "second":[string...], "third":[string...], "plural":[string...]},
+//    "past": {"first":[string...], "second":[string...], "third":[string...], "plural":[string...]}
+//  },
+//  "subjunctive": {...},
+//  "imperative": {...}
 // }
 func TestGetInflections_v1_exists(t *testing.T) {
 	clearDatabase(t)
 	// We'll request a verb that has forms in each grammatical category. Thus,
 	// the resulting JSON response should have entries in each position.
-	infinitive, _ := createCompleteVerb(t)
+	langId, infinitive := createCompleteVerb(t)
 
-	req, _ := http.NewRequest("GET", "/api/v1/words/"+infinitive+"/inflections", nil)
+	req, _ := http.NewRequest("GET", wordPath(langId, infinitive)+"/inflections",
+		nil)
 	resp := sendRequest(req)
 	checkCode(t, http.StatusOK, resp.Code)
 
@@ -232,11 +654,21 @@ func TestGetInflections_v1_exists(t *testing.T) {
 	json.Unmarshal(resp.Body.Bytes(), &table)
 
 	missingDataError := errors.New("Table is missing category information")
-	if table.Infinitive == "" || table.Present == nil || table.Past == nil {
+	if table.Infinitive == "" || table.Indicative == nil ||
+		table.Subjunctive == nil || table.Imperative == nil {
+		t.Fatal(missingDataError)
+	}
+	if len(table.Imperative.Present.Second) == 0 {
 		t.Error(missingDataError)
 	}
+	if table.Auxiliary != "hebben" || table.NonFinite == nil ||
+		table.NonFinite.PastParticiple != "gekregen" {
+		t.Error("Table is missing the auxiliary or past participle")
+	}
 
-	tenses := []*model.TenseInflection{table.Present, table.Past}
+	tenses := []*model.TenseInflection{
+		table.Indicative.Present, table.Indicative.Past,
+	}
 	for _, tense := range tenses {
 		if len(tense.First) == 0 ||
 			len(tense.Second) == 0 ||
@@ -247,7 +679,163 @@ func TestGetInflections_v1_exists(t *testing.T) {
 	}
 }
 
-// TODO: Test GET /words/{word}/inflections using all forms of a verb.
+// APIv1 should fill the missing forms of weak verbs with generated ones and
+// mark them, but leave irregular verbs alone.
+func TestGetInflections_v1_generated(t *testing.T) {
+	clearDatabase(t)
+	langId, infinitive := createCompleteVerb(t)
+	createWeakVerb(t)
+
+	req, _ := http.NewRequest("GET", wordPath(langId, "werken")+"/inflections",
+		nil)
+	resp := sendRequest(req)
+	checkCode(t, http.StatusOK, resp.Code)
+
+	var table model.ConjugationTable
+	json.Unmarshal(resp.Body.Bytes(), &table)
+	if len(table.Indicative.Past.First) != 1 ||
+		table.Indicative.Past.First[0] != "werkte" ||
+		table.NonFinite.PastParticiple != "gewerkt" {
+		t.Error("Expected the past forms of 'werken' to be generated")
+	}
+
+	generated := strings.Join(table.Generated, ",")
+	if !strings.Contains(generated, "Indicative.Past.First") ||
+		strings.Contains(generated, "Indicative.Present.First") {
+		t.Error("Expected only missing forms to be marked, got", generated)
+	}
+
+	req, _ = http.NewRequest("GET", wordPath(langId, infinitive)+"/inflections",
+		nil)
+	resp = sendRequest(req)
+	checkCode(t, http.StatusOK, resp.Code)
+
+	table = model.ConjugationTable{}
+	json.Unmarshal(resp.Body.Bytes(), &table)
+	if len(table.Generated) != 0 {
+		t.Error("Expected no forms of a strong verb to be generated, got",
+			table.Generated)
+	}
+}
+
+// APIv1 should report how often the rules agree with imported weak verbs.
+func TestGetRuleAccuracy_v1(t *testing.T) {
+	clearDatabase(t)
+	langId, _ := createCompleteVerb(t)
+	createWeakVerb(t)
+	token := requestAccount(t, "user", "pass")
+
+	req, _ := http.NewRequest("GET", "/api/v1/languages/"+strconv.Itoa(langId)+
+		"/rules/accuracy", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp := sendRequest(req)
+	checkCode(t, http.StatusOK, resp.Code)
+
+	var accuracy model.RuleAccuracy
+	json.Unmarshal(resp.Body.Bytes(), &accuracy)
+	if accuracy.Verbs != 1 || accuracy.Checked != 4 || accuracy.Correct != 4 {
+		t.Errorf("Expected 4 of 4 forms of 1 verb to be right, got %d of %d "+
+			"of %d", accuracy.Correct, accuracy.Checked, accuracy.Verbs)
+	}
+}
+
+// APIv1 should build the requested compound tenses of a verb from its
+// auxiliary, past participle, and the forms of the future auxiliary.
+func TestGetInflections_v1_compound(t *testing.T) {
+	clearDatabase(t)
+	langId, infinitive := createCompleteVerb(t)
+	createAuxiliaries(t)
+
+	req, _ := http.NewRequest("GET", wordPath(langId, infinitive)+
+		"/inflections?tenses=perfect,pluperfect,future,conditional", nil)
+	resp := sendRequest(req)
+	checkCode(t, http.StatusOK, resp.Code)
+
+	var table model.ConjugationTable
+	json.Unmarshal(resp.Body.Bytes(), &table)
+
+	expected := map[string]*model.TenseInflection{
+		"heb gekregen": table.Perfect,
+		"had gekregen": table.Pluperfect,
+		"zal krijgen":  table.Future,
+		"zou krijgen":  table.Conditional,
+	}
+	for form, tense := range expected {
+		if tense == nil || len(tense.First) != 1 || tense.First[0] != form {
+			t.Errorf("Expected first person form '%s'", form)
+		}
+	}
+}
+
+// APIv1 should only include compound tenses that are requested and reject
+// tenses that it does not know.
+func TestGetInflections_v1_compoundSelection(t *testing.T) {
+	clearDatabase(t)
+	langId, infinitive := createCompleteVerb(t)
+
+	req, _ := http.NewRequest("GET", wordPath(langId, infinitive)+"/inflections",
+		nil)
+	resp := sendRequest(req)
+	checkCode(t, http.StatusOK, resp.Code)
+
+	var table model.ConjugationTable
+	json.Unmarshal(resp.Body.Bytes(), &table)
+	if table.Perfect != nil || table.Future != nil {
+		t.Error("Compound tenses were included without being requested")
+	}
+
+	req, _ = http.NewRequest("GET", wordPath(langId, infinitive)+
+		"/inflections?tenses=aorist", nil)
+	resp = sendRequest(req)
+	checkCode(t, http.StatusBadRequest, resp.Code)
+}
+
+// APIv1 should keep the plural persons of German verbs apart, in simple and
+// compound tenses alike.
+func TestGetInflections_v1_german(t *testing.T) {
+	clearDatabase(t)
+	langId, infinitive := createGermanVerb(t)
+
+	req, _ := http.NewRequest("GET", wordPath(langId, infinitive)+
+		"/inflections?tenses=perfect,future", nil)
+	resp := sendRequest(req)
+	checkCode(t, http.StatusOK, resp.Code)
+
+	var table model.ConjugationTable
+	json.Unmarshal(resp.Body.Bytes(), &table)
+	if table.Indicative == nil || table.Perfect == nil || table.Future == nil {
+		t.Fatal("Table is missing category information")
+	}
+
+	expected := map[string]*model.TenseInflection{
+		"machen":        table.Indicative.Present,
+		"haben gemacht": table.Perfect,
+		"werden machen": table.Future,
+	}
+	for form, tense := range expected {
+		if len(tense.Plural) != 0 || len(tense.PluralFirst) != 1 ||
+			tense.PluralFirst[0] != form || len(tense.PluralThird) != 1 ||
+			len(tense.PluralSecond) != 1 || tense.PluralSecond[0] == form {
+			t.Errorf("Expected '%s' to be apart from the second person, got %v",
+				form, tense)
+		}
+	}
+}
+
+// APIv1 should only find the inflections of a verb in the language that
+// it belongs to.
+func TestGetInflections_v1_otherLanguage(t *testing.T) {
+	clearDatabase(t)
+	_, infinitive := createCompleteVerb(t)
+	otherLangId, _ := addLanguage(t)
+
+	req, _ := http.NewRequest("GET", wordPath(otherLangId, infinitive)+
+		"/inflections", nil)
+	resp := sendRequest(req)
+	checkCode(t, http.StatusNotFound, resp.Code)
+}
+
+// TODO: Test GET /languages/{id}/words/{word}/inflections using all forms of a verb.
 //       Right now the tests only check the infinitive, but we should ensure
 //       that API calls that use the various forms also retrieve the same
 //       table.
@@ -299,3 +887,591 @@ func TestGetSession_invalidCredentials(t *testing.T) {
 		t.Error("Expected error response from bad GET /tokens")
 	}
 }
+
+// APIv1 should trade a refresh token for new tokens only once. Reusing one
+// should revoke every token of the user.
+func TestRefreshToken_v1(t *testing.T) {
+	clearDatabase(t)
+	_, user, pass := createUser(t)
+
+	req, _ := http.NewRequest("GET", "/api/v1/tokens", nil)
+	cred := base64.StdEncoding.EncodeToString([]byte(user + ":" + pass))
+	req.Header.Set("Authorization", "Basic "+cred)
+	resp := sendRequest(req)
+	checkCode(t, http.StatusOK, resp.Code)
+
+	var first model.Tokens
+	json.Unmarshal(resp.Body.Bytes(), &first)
+	if first.RefreshToken == "" || first.ExpiresIn != 3600 {
+		t.Fatal("Expected a refresh token, got", string(resp.Body.Bytes()))
+	}
+
+	refresh := func(refreshToken string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", "/api/v1/tokens/refresh",
+			strings.NewReader(`{"refreshToken": "`+refreshToken+`"}`))
+		return sendRequest(req)
+	}
+
+	resp = refresh(first.RefreshToken)
+	checkCode(t, http.StatusOK, resp.Code)
+	var second model.Tokens
+	json.Unmarshal(resp.Body.Bytes(), &second)
+	if second.Token == "" || second.RefreshToken == first.RefreshToken {
+		t.Error("Expected new tokens, got", string(resp.Body.Bytes()))
+	}
+
+	checkCode(t, http.StatusUnauthorized, refresh(first.RefreshToken).Code)
+	checkCode(t, http.StatusUnauthorized, refresh(second.RefreshToken).Code)
+	checkCode(t, http.StatusUnauthorized, refresh("not_a_token").Code)
+}
+
+// APIv1 should stop accepting the tokens of a user who signs out everywhere.
+func TestRevokeTokens_v1(t *testing.T) {
+	clearDatabase(t)
+	userId, user, pass := createUser(t)
+	token := requestToken(t, user, pass)
+
+	getUser := func(token string) int {
+		req, _ := http.NewRequest("GET", "/api/v1/users/"+userId, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		return sendRequest(req).Code
+	}
+	checkCode(t, http.StatusOK, getUser(token))
+
+	req, _ := http.NewRequest("DELETE", "/api/v1/tokens", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	checkCode(t, http.StatusNoContent, sendRequest(req).Code)
+
+	checkCode(t, http.StatusUnauthorized, getUser(token))
+	checkCode(t, http.StatusOK, getUser(requestToken(t, user, pass)))
+}
+
+// APIv1 should hand out drills in the target language of a user and only
+// show them to that user.
+func TestCreateDrill_v1(t *testing.T) {
+	clearDatabase(t)
+	langId, infinitive := createCompleteVerb(t)
+	_, token := createLearner(t, langId)
+
+	req, _ := http.NewRequest("POST", "/api/v1/drills", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp := sendRequest(req)
+	checkCode(t, http.StatusCreated, resp.Code)
+
+	var drill model.Drill
+	json.Unmarshal(resp.Body.Bytes(), &drill)
+	if drill.LanguageId != langId || drill.Infinitive != infinitive ||
+		!strings.HasPrefix(drill.Prompt, infinitive+", ") {
+		t.Error("Expected a drill of", infinitive, "got", drill)
+	}
+
+	location := resp.Header().Get("Location")
+	req, _ = http.NewRequest("GET", location, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp = sendRequest(req)
+	checkCode(t, http.StatusOK, resp.Code)
+
+	_, otherToken := createLearner(t, langId)
+	req, _ = http.NewRequest("GET", location, nil)
+	req.Header.Set("Authorization", "Bearer "+otherToken)
+	resp = sendRequest(req)
+	checkCode(t, http.StatusNotFound, resp.Code)
+}
+
+// APIv1 should not give drills to users who haven't chosen a language.
+func TestCreateDrill_v1_noTarget(t *testing.T) {
+	clearDatabase(t)
+	createCompleteVerb(t)
+	token := requestAccount(t, "user", "pass")
+
+	req, _ := http.NewRequest("POST", "/api/v1/drills", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp := sendRequest(req)
+	checkCode(t, http.StatusBadRequest, resp.Code)
+}
+
+// APIv1 should check answers against the stored forms, explain wrong ones,
+// and accept only one answer per drill.
+func TestAnswerDrill_v1(t *testing.T) {
+	clearDatabase(t)
+	langId, _ := createCompleteVerb(t)
+	userId, token := createLearner(t, langId)
+
+	answer := func(drillId int, text string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", "/api/v1/drills/"+
+			strconv.Itoa(drillId)+"/answers",
+			strings.NewReader(`{"answer": "`+text+`"}`))
+		req.Header.Set("Authorization", "Bearer "+token)
+		return sendRequest(req)
+	}
+
+	drillId := createDrill(t, userId)
+	resp := answer(drillId, " Kreeg ")
+	checkCode(t, http.StatusOK, resp.Code)
+
+	var result model.DrillResult
+	json.Unmarshal(resp.Body.Bytes(), &result)
+	if result.Drill == nil || result.Correct == nil || !*result.Correct ||
+		len(result.Expected) != 1 || result.Expected[0] != "kreeg" {
+		t.Error("Expected 'kreeg' to be correct, got", string(resp.Body.Bytes()))
+	}
+
+	resp = answer(drillId, "kreeg")
+	checkCode(t, http.StatusConflict, resp.Code)
+
+	resp = answer(createDrill(t, userId), "kregen")
+	checkCode(t, http.StatusOK, resp.Code)
+
+	result = model.DrillResult{}
+	json.Unmarshal(resp.Body.Bytes(), &result)
+	if result.Drill == nil || result.Correct == nil || *result.Correct ||
+		len(result.Readings) == 0 || result.Readings[0].Number != "plural" {
+		t.Error("Expected 'kregen' to be wrong and read as plural, got",
+			string(resp.Body.Bytes()))
+	}
+}
+
+// APIv1 should add new cards to a user's deck when too few are due, but no
+// more than the daily limit.
+func TestGetDueCards_v1(t *testing.T) {
+	clearDatabase(t)
+	langId, _ := createCompleteVerb(t)
+	_, token := createLearner(t, langId)
+
+	getDue := func() []model.Card {
+		req, _ := http.NewRequest("GET", "/api/v1/cards/due?limit=5&new=2", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp := sendRequest(req)
+		checkCode(t, http.StatusOK, resp.Code)
+
+		var cards []model.Card
+		json.Unmarshal(resp.Body.Bytes(), &cards)
+		return cards
+	}
+
+	cards := getDue()
+	if len(cards) != 2 {
+		t.Fatal("Expected 2 new cards, got", len(cards))
+	}
+	for _, card := range cards {
+		if len(card.Forms) == 0 || card.Prompt == "" {
+			t.Error("Expected card to have a prompt and forms, got", card)
+		}
+	}
+
+	again := getDue()
+	if len(again) != 2 || again[0].Id != cards[0].Id ||
+		again[1].Id != cards[1].Id {
+		t.Error("Expected the same cards once the daily limit was reached")
+	}
+}
+
+// APIv1 should schedule a card after it is reviewed and reject bad grades.
+func TestReviewCard_v1(t *testing.T) {
+	clearDatabase(t)
+	langId, _ := createCompleteVerb(t)
+	_, token := createLearner(t, langId)
+
+	req, _ := http.NewRequest("GET", "/api/v1/cards/due?limit=1&new=1", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp := sendRequest(req)
+	checkCode(t, http.StatusOK, resp.Code)
+
+	var cards []model.Card
+	json.Unmarshal(resp.Body.Bytes(), &cards)
+	if len(cards) != 1 {
+		t.Fatal("Expected a new card")
+	}
+
+	review := func(token, grade string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", "/api/v1/cards/"+
+			strconv.Itoa(cards[0].Id)+"/reviews",
+			strings.NewReader(`{"grade": `+grade+`}`))
+		req.Header.Set("Authorization", "Bearer "+token)
+		return sendRequest(req)
+	}
+
+	resp = review(token, "4")
+	checkCode(t, http.StatusOK, resp.Code)
+
+	var card model.Card
+	json.Unmarshal(resp.Body.Bytes(), &card)
+	if card.Repetitions != 1 || card.Interval != 1 || card.ReviewedAt == nil ||
+		!card.Due.After(*card.ReviewedAt) {
+		t.Error("Expected the card to be due a day later, got", card)
+	}
+
+	req, _ = http.NewRequest("GET", "/api/v1/cards/due?new=0", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp = sendRequest(req)
+	checkCode(t, http.StatusNotFound, resp.Code)
+
+	checkCode(t, http.StatusBadRequest, review(token, "6").Code)
+
+	_, otherToken := createLearner(t, langId)
+	checkCode(t, http.StatusNotFound, review(otherToken, "4").Code)
+}
+
+// APIv1 should count recorded results and answered drills in a user's stats.
+func TestGetStats_v1(t *testing.T) {
+	clearDatabase(t)
+	langId, _ := createCompleteVerb(t)
+	userId, token := createLearner(t, langId)
+	lang := strconv.Itoa(langId)
+
+	record := func(body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", "/api/v1/users/"+userId+"/results",
+			strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		return sendRequest(req)
+	}
+
+	resp := record(`[
+		{"language": ` + lang + `, "infinitive": "krijgen", "tense": "present",
+		 "person": 1, "number": "singular", "correct": true},
+		{"language": ` + lang + `, "infinitive": "krijgen", "tense": "present",
+		 "number": "plural", "correct": false}
+	]`)
+	checkCode(t, http.StatusCreated, resp.Code)
+
+	resp = record(`[{"language": ` + lang + `, "infinitive": "krijgen",
+		"tense": "future", "number": "plural", "correct": true}]`)
+	checkCode(t, http.StatusBadRequest, resp.Code)
+
+	req, _ := http.NewRequest("POST", "/api/v1/drills/"+
+		strconv.Itoa(createDrill(t, userId))+"/answers",
+		strings.NewReader(`{"answer": "kreeg"}`))
+	req.Header.Set("Authorization", "Bearer "+token)
+	checkCode(t, http.StatusOK, sendRequest(req).Code)
+
+	req, _ = http.NewRequest("GET", "/api/v1/users/"+userId+"/stats", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp = sendRequest(req)
+	checkCode(t, http.StatusOK, resp.Code)
+
+	var stats model.Stats
+	json.Unmarshal(resp.Body.Bytes(), &stats)
+	if stats.Total != 3 || stats.Correct != 2 {
+		t.Error("Expected 2 of 3 results to be correct, got", stats.Tally)
+	}
+	if len(stats.ByTense) != 2 || stats.ByTense[0].Tense != "present" ||
+		stats.ByTense[0].Total != 2 {
+		t.Error("Expected 2 present and 1 past result, got",
+			string(resp.Body.Bytes()))
+	}
+	if len(stats.ByPerson) != 3 {
+		t.Error("Expected results in 3 persons, got", len(stats.ByPerson))
+	}
+	if len(stats.MostMissed) != 1 || stats.MostMissed[0].Misses != 1 ||
+		stats.MostMissed[0].Infinitive != "krijgen" {
+		t.Error("Expected 'krijgen' to be missed once, got", stats.MostMissed)
+	}
+	if len(stats.Daily) != 1 || stats.Daily[0].Total != 3 ||
+		stats.CurrentStreak != 1 || stats.LongestStreak != 1 {
+		t.Error("Expected a streak of one day, got", string(resp.Body.Bytes()))
+	}
+}
+
+// APIv1 should only show stats to their user and admins.
+func TestGetStats_v1_forbidden(t *testing.T) {
+	clearDatabase(t)
+	langId, _ := createCompleteVerb(t)
+	userId, _ := createLearner(t, langId)
+	_, otherToken := createLearner(t, langId)
+
+	req, _ := http.NewRequest("GET", "/api/v1/users/"+userId+"/stats", nil)
+	req.Header.Set("Authorization", "Bearer "+otherToken)
+	checkCode(t, http.StatusUnauthorized, sendRequest(req).Code)
+}
+
+// sendJson sends a request with a JSON body and a bearer token.
+func sendJson(method, path, token, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+	return sendRequest(req)
+}
+
+// APIv1 should let admins edit words and record each change in the word's
+// history.
+func TestEditWord_v1(t *testing.T) {
+	clearDatabase(t)
+	langId, _ := createCompleteVerb(t)
+	lang := strconv.Itoa(langId)
+	adminId, token := createAdmin(t)
+	_, userToken := createLearner(t, langId)
+
+	resp := sendJson("POST", "/api/v1/words", userToken,
+		`{"language": `+lang+`, "text": "lopen"}`)
+	checkCode(t, http.StatusForbidden, resp.Code)
+
+	resp = sendJson("POST", "/api/v1/words", token,
+		`{"language": `+lang+`, "text": "lopen"}`)
+	checkCode(t, http.StatusCreated, resp.Code)
+	var word model.Word
+	json.Unmarshal(resp.Body.Bytes(), &word)
+	path := "/api/v1/words/" + strconv.Itoa(word.Id)
+
+	resp = sendJson("POST", "/api/v1/words", token,
+		`{"language": `+lang+`, "text": "lopen"}`)
+	checkCode(t, http.StatusConflict, resp.Code)
+
+	resp = sendJson("PUT", path, token, `{"text": "loopen"}`)
+	checkCode(t, http.StatusOK, resp.Code)
+	resp = sendJson("DELETE", path, token, "")
+	checkCode(t, http.StatusNoContent, resp.Code)
+
+	resp = sendJson("DELETE",
+		"/api/v1/words/"+strconv.Itoa(getWordId(t, "krijgen")), token, "")
+	checkCode(t, http.StatusConflict, resp.Code)
+
+	resp = sendJson("GET", path+"/history", token, "")
+	checkCode(t, http.StatusOK, resp.Code)
+	var history []model.AuditEntry
+	json.Unmarshal(resp.Body.Bytes(), &history)
+	if len(history) != 3 {
+		t.Fatal("Expected 3 changes, got", string(resp.Body.Bytes()))
+	}
+	for i, action := range []string{"create", "update", "delete"} {
+		if history[i].Action != action || history[i].EditorId != adminId {
+			t.Errorf("Expected change %d to be a %s by the admin, got %v",
+				i, action, history[i])
+		}
+	}
+	if !strings.Contains(string(history[1].Before), "lopen") ||
+		!strings.Contains(string(history[1].After), "loopen") {
+		t.Error("Expected the update to record both spellings, got",
+			history[1])
+	}
+
+	// Imports lock words while they keep their ids in memory.
+	ctx := context.Background()
+	importConn, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer importConn.Close()
+	const wordsLock = 0x776f726473
+	importConn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, wordsLock)
+	defer importConn.ExecContext(ctx, `SELECT pg_advisory_unlock($1)`,
+		wordsLock)
+
+	resp = sendJson("POST", "/api/v1/words", token,
+		`{"language": `+lang+`, "text": "rennen"}`)
+	checkCode(t, http.StatusServiceUnavailable, resp.Code)
+}
+
+// APIv1 should let admins add, change, and remove verb forms, filing each
+// change under the infinitive.
+func TestEditVerbForm_v1(t *testing.T) {
+	clearDatabase(t)
+	langId, _ := createCompleteVerb(t)
+	lang := strconv.Itoa(langId)
+	_, token := createAdmin(t)
+
+	form := `{"language": ` + lang + `, "word": "krijgde",
+		"infinitive": "krijgen", "tense": "past", "persons": [1, 2, 3],
+		"number": "singular"}`
+	resp := sendJson("POST", "/api/v1/verb-forms", token, form)
+	checkCode(t, http.StatusCreated, resp.Code)
+	var created model.VerbForm
+	json.Unmarshal(resp.Body.Bytes(), &created)
+	if created.Mood != "indicative" || len(created.Persons) != 3 {
+		t.Error("Expected a form of three persons, got", created)
+	}
+	path := "/api/v1/verb-forms/" + strconv.Itoa(created.Id)
+
+	checkCode(t, http.StatusConflict,
+		sendJson("POST", "/api/v1/verb-forms", token, form).Code)
+	checkCode(t, http.StatusBadRequest,
+		sendJson("POST", "/api/v1/verb-forms", token, `{"language": `+lang+`,
+			"word": "krijgde", "infinitive": "krijgen", "tense": "past",
+			"number": "singular"}`).Code)
+
+	// Pretend the form was imported; the edit should detach it from its page.
+	db.Exec(`UPDATE verb_forms SET page_id = 7 WHERE id = $1`, created.Id)
+	resp = sendJson("PUT", path, token, `{"language": `+lang+`,
+		"word": "krijgden", "infinitive": "krijgen", "tense": "past",
+		"number": "plural"}`)
+	checkCode(t, http.StatusOK, resp.Code)
+	var pageId *int
+	db.QueryRow(`SELECT page_id FROM verb_forms WHERE id = $1`,
+		created.Id).Scan(&pageId)
+	if pageId != nil {
+		t.Error("Edited forms should not belong to an archive page")
+	}
+
+	resp = sendJson("GET", path, "", "")
+	checkCode(t, http.StatusOK, resp.Code)
+	var updated model.VerbForm
+	json.Unmarshal(resp.Body.Bytes(), &updated)
+	if updated.Word != "krijgden" || updated.Number != "plural" ||
+		len(updated.Persons) != 0 {
+		t.Error("Expected the form to become plural, got", updated)
+	}
+
+	checkCode(t, http.StatusNoContent, sendJson("DELETE", path, token, "").Code)
+	checkCode(t, http.StatusNotFound, sendJson("DELETE", path, token, "").Code)
+
+	// Imports should skip the replaced and the deleted form.
+	var tombstones int
+	db.QueryRow(`SELECT count(*) FROM deleted_verb_forms`).Scan(&tombstones)
+	if tombstones != 2 {
+		t.Error("Expected 2 tombstones, found", tombstones)
+	}
+
+	resp = sendJson("GET", "/api/v1/words/"+
+		strconv.Itoa(getWordId(t, "krijgen"))+"/history", token, "")
+	checkCode(t, http.StatusOK, resp.Code)
+	var history []model.AuditEntry
+	json.Unmarshal(resp.Body.Bytes(), &history)
+	if len(history) != 3 || history[0].Table != "verb_forms" {
+		t.Error("Expected 3 changes to verb forms, got",
+			string(resp.Body.Bytes()))
+	}
+}
+
+// APIv1 should let users propose corrections to verb forms and let admins
+// accept or reject them.
+func TestCorrections_v1(t *testing.T) {
+	clearDatabase(t)
+	langId, verb := createCompleteVerb(t)
+	lang := strconv.Itoa(langId)
+	_, token := createAdmin(t)
+	_, userToken := createLearner(t, langId)
+
+	var formId int
+	err := db.QueryRow(`
+		SELECT vf.id FROM verb_forms vf
+		JOIN words ON words.id = vf.word_id
+		WHERE words.word = 'kreegt'`,
+	).Scan(&formId)
+	checkError(t, err)
+	path := "/api/v1/words/" + verb + "/corrections"
+
+	resp := sendJson("POST", path, userToken, `{"language": `+lang+`,
+		"replaces": `+strconv.Itoa(formId)+`, "word": "kreegd",
+		"tense": "past", "persons": [2], "number": "singular",
+		"comment": "typo"}`)
+	checkCode(t, http.StatusCreated, resp.Code)
+	var proposed model.Correction
+	json.Unmarshal(resp.Body.Bytes(), &proposed)
+	if proposed.Status != model.CorrectionPending ||
+		proposed.Form.Infinitive != verb {
+		t.Error("Expected a pending correction of", verb, "got", proposed)
+	}
+
+	checkCode(t, http.StatusBadRequest,
+		sendJson("POST", path, userToken, `{"language": `+lang+`,
+			"word": "kreegd", "tense": "past", "number": "singular"}`).Code)
+	checkCode(t, http.StatusBadRequest,
+		sendJson("POST", "/api/v1/words/lopen/corrections", userToken,
+			`{"language": `+lang+`, "word": "liep", "tense": "past",
+			"persons": [1], "number": "singular"}`).Code)
+
+	resp = sendJson("POST", path, userToken, `{"language": `+lang+`,
+		"word": "krijgde", "tense": "past", "persons": [1],
+		"number": "singular"}`)
+	checkCode(t, http.StatusCreated, resp.Code)
+	var addition model.Correction
+	json.Unmarshal(resp.Body.Bytes(), &addition)
+
+	checkCode(t, http.StatusForbidden,
+		sendJson("GET", "/api/v1/corrections", userToken, "").Code)
+	resp = sendJson("GET", "/api/v1/corrections", token, "")
+	checkCode(t, http.StatusOK, resp.Code)
+	var queue []model.Correction
+	json.Unmarshal(resp.Body.Bytes(), &queue)
+	if len(queue) != 2 || queue[0].Id != proposed.Id {
+		t.Error("Expected 2 pending corrections, got", queue)
+	}
+
+	review := "/api/v1/corrections/" + strconv.Itoa(proposed.Id)
+	resp = sendJson("PATCH", review, token, `{"status": "accepted"}`)
+	checkCode(t, http.StatusOK, resp.Code)
+	checkCode(t, http.StatusConflict,
+		sendJson("PATCH", review, token, `{"status": "rejected"}`).Code)
+
+	resp = sendJson("GET", "/api/v1/verb-forms/"+strconv.Itoa(formId), "", "")
+	checkCode(t, http.StatusOK, resp.Code)
+	var applied model.VerbForm
+	json.Unmarshal(resp.Body.Bytes(), &applied)
+	if applied.Word != "kreegd" {
+		t.Error("Expected the correction to be applied, got", applied)
+	}
+
+	checkCode(t, http.StatusOK, sendJson("PATCH", "/api/v1/corrections/"+
+		strconv.Itoa(addition.Id), token, `{"status": "rejected"}`).Code)
+	resp = sendJson("GET", "/api/v1/corrections?status=rejected", token, "")
+	checkCode(t, http.StatusOK, resp.Code)
+	json.Unmarshal(resp.Body.Bytes(), &queue)
+	if len(queue) != 1 || queue[0].Id != addition.Id {
+		t.Error("Expected 1 rejected correction, got", queue)
+	}
+	checkCode(t, http.StatusNotFound,
+		sendJson("GET", "/api/v1/corrections", token, "").Code)
+}
+
+// APIv1 should answer clients that request too many tokens with a too many
+// requests code and say when they can try again.
+func TestGetToken_v1_rateLimit(t *testing.T) {
+	clearDatabase(t)
+	cred := base64.StdEncoding.EncodeToString([]byte("user:wrong"))
+	requestFrom := func(addr string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", "/api/v1/tokens", nil)
+		req.Header.Set("Authorization", "Basic "+cred)
+		req.RemoteAddr = addr
+		return sendRequest(req)
+	}
+
+	// Clients can request 10 tokens a minute.
+	for i := 0; i < 10; i++ {
+		checkCode(t, http.StatusUnauthorized, requestFrom("10.0.0.1:1234").Code)
+	}
+	resp := requestFrom("10.0.0.1:5678")
+	checkCode(t, http.StatusTooManyRequests, resp.Code)
+	if resp.Header().Get("Retry-After") != "6" {
+		t.Error("Expected to retry after 6 seconds, got",
+			resp.Header().Get("Retry-After"))
+	}
+
+	checkCode(t, http.StatusUnauthorized, requestFrom("10.0.0.2:1234").Code)
+}
+
+// Behind a proxy, clients should be told apart by the address that the
+// proxy adds to ClientIPHeader rather than by the proxy's own address.
+func TestGetToken_v1_rateLimitBehindProxy(t *testing.T) {
+	clearDatabase(t)
+	service.ClientIPHeader = "X-Forwarded-For"
+	defer func() { service.ClientIPHeader = "" }()
+
+	cred := base64.StdEncoding.EncodeToString([]byte("user:wrong"))
+	requestFor := func(forwarded string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", "/api/v1/tokens", nil)
+		req.Header.Set("Authorization", "Basic "+cred)
+		req.Header.Set("X-Forwarded-For", forwarded)
+		req.RemoteAddr = "10.0.0.1:1234"
+		return sendRequest(req)
+	}
+
+	// Clients can't pick their own bucket by sending the header themselves.
+	for i := 0; i < 10; i++ {
+		forwarded := strconv.Itoa(i) + ".0.0.0, 192.0.2.1"
+		checkCode(t, http.StatusUnauthorized, requestFor(forwarded).Code)
+	}
+	checkCode(t, http.StatusTooManyRequests, requestFor("192.0.2.1").Code)
+	checkCode(t, http.StatusUnauthorized, requestFor("192.0.2.2").Code)
+}
+
+// The service should publish the public keys that verify its tokens. Tests
+// sign tokens with a secret, which must not be published.
+func TestGetPublicKeys(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/.well-known/jwks.json", nil)
+	resp := sendRequest(req)
+	checkCode(t, http.StatusOK, resp.Code)
+
+	var keys model.JWKSet
+	json.Unmarshal(resp.Body.Bytes(), &keys)
+	if keys.Keys == nil || len(keys.Keys) != 0 {
+		t.Error("Expected an empty key set, got", string(resp.Body.Bytes()))
+	}
+}
@@ -0,0 +1,143 @@
+package controller
+
+import (
+	"encoding/json"
+	"log"
+	"mutably/api/controller/ratelimit"
+	"mutably/api/model"
+	"net/http"
+	"time"
+)
+
+// credentialLimit slows down clients that guess passwords or tokens.
+var credentialLimit = ratelimit.Limit{Requests: 10, Per: time.Minute}
+
+// Tokens is a Controller that signs users in and out.
+type Tokens struct {
+	db   model.Database
+	auth *model.AuthLayer
+}
+
+func (t *Tokens) Routes() []Route {
+	return []Route{
+		{ // GET /api/v1/tokens
+			Version:     "v1",
+			Path:        "/tokens",
+			Method:      "GET",
+			Handler:     t.getToken,
+			IsProtected: false,
+			Limit:       credentialLimit,
+		},
+		{ // POST /api/v1/tokens/refresh
+			Version:     "v1",
+			Path:        "/tokens/refresh",
+			Method:      "POST",
+			Handler:     t.refreshToken,
+			IsProtected: false,
+			Limit:       credentialLimit,
+		},
+		{ // DELETE /api/v1/tokens
+			Version:     "v1",
+			Path:        "/tokens",
+			Method:      "DELETE",
+			Handler:     t.revokeTokens,
+			IsProtected: true,
+		},
+	}
+}
+
+// respondWithTokens signs a user in.
+func respondWithTokens(w http.ResponseWriter, auth *model.AuthLayer,
+	code int, userId string) {
+	tokens, err := auth.IssueTokens(userId)
+	if err != nil {
+		log.Println(err)
+		makeErrorResponse(w, http.StatusInternalServerError,
+			"could not issue tokens")
+		return
+	}
+	makeJsonResponse(w, code, tokens)
+}
+
+// GET /api/v1/tokens
+func (t *Tokens) getToken(w http.ResponseWriter, r *http.Request) {
+	username, password, err := t.auth.GetCredentials(r)
+	if err != nil {
+		makeErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	userId := t.db.GetUserId(username, password)
+	if userId == "" {
+		makeErrorResponse(w, http.StatusUnauthorized,
+			"invalid user credentials")
+	} else {
+		respondWithTokens(w, t.auth, http.StatusOK, userId)
+	}
+}
+
+// POST /api/v1/tokens/refresh
+// {"refreshToken": "..."}
+func (t *Tokens) refreshToken(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		RefreshToken string `json:"refreshToken"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil ||
+		body.RefreshToken == "" {
+		makeErrorResponse(w, http.StatusBadRequest,
+			`expected a body of the form {"refreshToken": "..."}`)
+		return
+	}
+
+	tokens, err := t.auth.Refresh(body.RefreshToken)
+	switch {
+	case err == model.ErrInvalidRefreshToken:
+		makeErrorResponse(w, http.StatusUnauthorized, err.Error())
+	case err != nil:
+		log.Println(err)
+		makeErrorResponse(w, http.StatusInternalServerError,
+			"could not refresh tokens")
+	default:
+		makeJsonResponse(w, http.StatusOK, tokens)
+	}
+}
+
+// DELETE /api/v1/tokens
+// {"refreshToken": "..."}
+//
+// Without a body, every token of the user is revoked.
+func (t *Tokens) revokeTokens(w http.ResponseWriter, r *http.Request) {
+	userId, err := requestUserId(t.auth, r)
+	if err != nil {
+		makeErrorResponse(w, http.StatusForbidden, err.Error())
+		return
+	}
+
+	var body struct {
+		RefreshToken string `json:"refreshToken"`
+	}
+	if r.ContentLength != 0 {
+		if err = json.NewDecoder(r.Body).Decode(&body); err != nil {
+			makeErrorResponse(w, http.StatusBadRequest,
+				`expected no body or one of the form {"refreshToken": "..."}`)
+			return
+		}
+	}
+
+	if err = t.auth.Revoke(userId, body.RefreshToken); err != nil {
+		log.Println(err)
+		makeErrorResponse(w, http.StatusInternalServerError,
+			"could not revoke tokens")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GET /.well-known/jwks.json
+//
+// Other services verify access tokens with these keys. The path is the
+// well-known location from RFC 8615 rather than one under /api.
+func (t *Tokens) getPublicKeys(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=60")
+	makeJsonResponse(w, http.StatusOK, t.auth.PublicKeys())
+}
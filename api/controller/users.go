@@ -1,12 +1,22 @@
 package controller
 
 import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"mutably/api/controller/ratelimit"
 	"mutably/api/model"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
 )
 
+// signUpLimit keeps clients from creating accounts in bulk.
+var signUpLimit = ratelimit.Limit{Requests: 5, Per: time.Hour}
+
 // Users is a Controller for the /users resource.
 type Users struct {
 	db   model.Database
@@ -21,6 +31,7 @@ func (u *Users) Routes() []Route {
 			Method:      "GET",
 			Handler:     u.getUsers,
 			IsProtected: true,
+			Role:        model.RoleAdmin,
 		},
 		{ // POST /api/v1/users
 			Version:     "v1",
@@ -28,6 +39,7 @@ func (u *Users) Routes() []Route {
 			Method:      "POST",
 			Handler:     u.createUser,
 			IsProtected: false,
+			Limit:       signUpLimit,
 		},
 		{ // GET /api/v1/users/{id}
 			Version:     "v1",
@@ -36,19 +48,63 @@ func (u *Users) Routes() []Route {
 			Handler:     u.getUser,
 			IsProtected: true,
 		},
+		{ // PATCH /api/v1/users/{id}
+			Version:     "v1",
+			Path:        "/users/{id}",
+			Method:      "PATCH",
+			Handler:     u.updateUser,
+			IsProtected: true,
+		},
+		{ // DELETE /api/v1/users/{id}
+			Version:     "v1",
+			Path:        "/users/{id}",
+			Method:      "DELETE",
+			Handler:     u.deleteUser,
+			IsProtected: true,
+		},
+		{ // PUT /api/v1/users/{id}/role
+			Version:     "v1",
+			Path:        "/users/{id}/role",
+			Method:      "PUT",
+			Handler:     u.setRole,
+			IsProtected: true,
+			Role:        model.RoleAdmin,
+		},
+		{ // PUT /api/v1/users/{id}/password
+			Version:     "v1",
+			Path:        "/users/{id}/password",
+			Method:      "PUT",
+			Handler:     u.changePassword,
+			IsProtected: true,
+			Limit:       credentialLimit,
+		},
+		{ // POST /api/v1/users/{id}/results
+			Version:     "v1",
+			Path:        "/users/{id}/results",
+			Method:      "POST",
+			Handler:     u.recordResults,
+			IsProtected: true,
+		},
+		{ // GET /api/v1/users/{id}/stats
+			Version:     "v1",
+			Path:        "/users/{id}/stats",
+			Method:      "GET",
+			Handler:     u.getStats,
+			IsProtected: true,
+		},
 	}
 }
 
-// GET /api/v1/users
+// GET /api/v1/users?limit=20&cursor=MjA&sort=-created&language=1
 func (u *Users) getUsers(w http.ResponseWriter, r *http.Request) {
-	claims, err := u.auth.GetClaims(r)
-	if err != nil || u.db.IsAdmin(claims["id"].(string)) {
-		users, err := u.db.GetUsers()
-		respondWithAggregate(w, users, len(users), err, "users")
-	} else {
-		makeErrorResponse(w, http.StatusForbidden,
-			"resource requires admin privileges")
+	opts, err := parseListOptions(r, model.UserSortFields)
+	if err != nil {
+		makeErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
 	}
+
+	users, err := u.db.GetUsers(peek(opts))
+	respondWithPage(w, r, users, opts, err, "users")
 }
 
 // POST /api/v1/users
@@ -66,28 +122,253 @@ func (u *Users) createUser(w http.ResponseWriter, r *http.Request) {
 		makeErrorResponse(w, http.StatusBadRequest, err.Error())
 		return
 	}
-	w.WriteHeader(http.StatusCreated)
-	u.auth.GenerateTokenWithClaim(w, map[string]interface{}{"id": userId})
+	respondWithTokens(w, u.auth, http.StatusCreated, userId)
 }
 
-// GET /api/v1/users/{id}
-func (u *Users) getUser(w http.ResponseWriter, r *http.Request) {
+// authorizeUser returns the {id} of r if the requester is that user or an
+// admin. Otherwise, it responds with an error and returns false.
+func (u *Users) authorizeUser(w http.ResponseWriter,
+	r *http.Request) (string, bool) {
 	claims, err := u.auth.GetClaims(r)
 	if err != nil {
 		makeErrorResponse(w, http.StatusForbidden, err.Error())
+		return "", false
+	}
+
+	id := mux.Vars(r)["id"]
+	isAdmin := model.ClaimedRole(claims).Includes(model.RoleAdmin)
+	if id == claims["id"] || isAdmin {
+		return id, true
+	}
+	makeErrorResponse(w, http.StatusUnauthorized, "insufficient permissions")
+	return "", false
+}
+
+// GET /api/v1/users/{id}
+func (u *Users) getUser(w http.ResponseWriter, r *http.Request) {
+	id, ok := u.authorizeUser(w, r)
+	if !ok {
 		return
 	}
 
-	vars := mux.Vars(r)
-	if vars["id"] == claims["id"] || u.db.IsAdmin(claims["id"].(string)) {
-		user, err := u.db.GetUser(vars["id"])
-		if err != nil {
-			makeErrorResponse(w, http.StatusNotFound, "user not found")
-		} else {
-			makeJsonResponse(w, http.StatusOK, user)
-		}
+	user, err := u.db.GetUser(id)
+	if err != nil {
+		makeErrorResponse(w, http.StatusNotFound, "user not found")
 	} else {
-		makeErrorResponse(w, http.StatusUnauthorized,
-			"insufficient permissions")
+		makeJsonResponse(w, http.StatusOK, user)
+	}
+}
+
+// validCredential reports whether s can be used as a name or password.
+// Credentials are sent as 'name:password', so neither may contain a colon.
+func validCredential(s string) bool {
+	return s != "" && !strings.Contains(s, ":")
+}
+
+// PATCH /api/v1/users/{id}
+// {"name": "anna", "targetLanguageId": 1}
+func (u *Users) updateUser(w http.ResponseWriter, r *http.Request) {
+	id, ok := u.authorizeUser(w, r)
+	if !ok {
+		return
+	}
+
+	// A null targetLanguageId clears the target language, while a missing
+	// one leaves it unchanged.
+	var body struct {
+		Name             *string         `json:"name"`
+		TargetLanguageId json.RawMessage `json:"targetLanguageId"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		makeErrorResponse(w, http.StatusBadRequest, "invalid user fields")
+		return
+	}
+
+	changes := model.UserChanges{Name: body.Name}
+	if body.Name != nil && !validCredential(*body.Name) {
+		makeErrorResponse(w, http.StatusBadRequest,
+			"name must be non-empty and contain no colons")
+		return
+	}
+	if body.TargetLanguageId != nil {
+		var languageId *int
+		if err := json.Unmarshal(body.TargetLanguageId, &languageId); err != nil {
+			makeErrorResponse(w, http.StatusBadRequest, "invalid language id")
+			return
+		}
+		changes.TargetLanguageId = &sql.NullInt64{}
+		if languageId != nil {
+			changes.TargetLanguageId.Int64 = int64(*languageId)
+			changes.TargetLanguageId.Valid = true
+		}
+	}
+
+	user, err := u.db.UpdateUser(id, changes)
+	switch {
+	case err == sql.ErrNoRows:
+		makeErrorResponse(w, http.StatusNotFound, "user not found")
+	case err == model.ErrNameTaken:
+		makeErrorResponse(w, http.StatusConflict, err.Error())
+	case err == model.ErrUnknownLanguage:
+		makeErrorResponse(w, http.StatusBadRequest, err.Error())
+	case err != nil:
+		log.Println(err)
+		makeErrorResponse(w, http.StatusInternalServerError,
+			"could not update user")
+	default:
+		makeJsonResponse(w, http.StatusOK, user)
+	}
+}
+
+// PUT /api/v1/users/{id}/role
+// {"role": "admin"}
+func (u *Users) setRole(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Role model.Role `json:"role"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil ||
+		body.Role == "" {
+		makeErrorResponse(w, http.StatusBadRequest,
+			`expected a body of the form {"role": "..."}`)
+		return
+	}
+
+	user, err := u.db.SetUserRole(mux.Vars(r)["id"], body.Role)
+	switch {
+	case err == sql.ErrNoRows:
+		makeErrorResponse(w, http.StatusNotFound, "user not found")
+	case err == model.ErrUnknownRole:
+		makeErrorResponse(w, http.StatusBadRequest, err.Error())
+	case err == model.ErrLastAdmin:
+		makeErrorResponse(w, http.StatusConflict, err.Error())
+	case err != nil:
+		log.Println(err)
+		makeErrorResponse(w, http.StatusInternalServerError,
+			"could not change role")
+	default:
+		makeJsonResponse(w, http.StatusOK, user)
+	}
+}
+
+// PUT /api/v1/users/{id}/password
+// {"oldPassword": "pwd123", "newPassword": "pwd456"}
+func (u *Users) changePassword(w http.ResponseWriter, r *http.Request) {
+	id, ok := u.authorizeUser(w, r)
+	if !ok {
+		return
+	}
+
+	var body struct {
+		OldPassword string `json:"oldPassword"`
+		NewPassword string `json:"newPassword"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil ||
+		body.OldPassword == "" {
+		makeErrorResponse(w, http.StatusBadRequest,
+			`expected a body of the form {"oldPassword": "...", "newPassword": "..."}`)
+		return
+	}
+	if !validCredential(body.NewPassword) {
+		makeErrorResponse(w, http.StatusBadRequest,
+			"password must be non-empty and contain no colons")
+		return
+	}
+
+	err := u.db.ChangePassword(id, body.OldPassword, body.NewPassword)
+	switch {
+	case err == model.ErrWrongPassword:
+		makeErrorResponse(w, http.StatusForbidden, err.Error())
+	case err != nil:
+		log.Println(err)
+		makeErrorResponse(w, http.StatusInternalServerError,
+			"could not change password")
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// DELETE /api/v1/users/{id}
+func (u *Users) deleteUser(w http.ResponseWriter, r *http.Request) {
+	id, ok := u.authorizeUser(w, r)
+	if !ok {
+		return
+	}
+
+	err := u.db.DeleteUser(id)
+	switch {
+	case err == sql.ErrNoRows:
+		makeErrorResponse(w, http.StatusNotFound, "user not found")
+	case err != nil:
+		log.Println(err)
+		makeErrorResponse(w, http.StatusInternalServerError,
+			"could not delete user")
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// POST /api/v1/users/{id}/results
+// [{"language": 1, "infinitive": "krijgen", "tense": "past", "person": 3,
+// "number": "singular", "correct": true}]
+func (u *Users) recordResults(w http.ResponseWriter, r *http.Request) {
+	id, ok := u.authorizeUser(w, r)
+	if !ok {
+		return
+	}
+
+	var results []*model.PracticeResult
+	if err := json.NewDecoder(r.Body).Decode(&results); err != nil ||
+		len(results) == 0 {
+		makeErrorResponse(w, http.StatusBadRequest,
+			"expected a non-empty list of practice results")
+		return
+	}
+
+	err := u.db.RecordResults(id, results)
+	switch {
+	case err == model.ErrUnknownCell:
+		makeErrorResponse(w, http.StatusBadRequest, err.Error())
+	case err != nil:
+		log.Println(err)
+		makeErrorResponse(w, http.StatusInternalServerError,
+			"could not record results")
+	default:
+		makeJsonResponse(w, http.StatusCreated,
+			map[string]int{"recorded": len(results)})
+	}
+}
+
+// GET /api/v1/users/{id}/stats?language=1&days=30
+func (u *Users) getStats(w http.ResponseWriter, r *http.Request) {
+	id, ok := u.authorizeUser(w, r)
+	if !ok {
+		return
+	}
+
+	params := r.URL.Query()
+	languageId, err := intParam(params.Get("language"), 0)
+	if err != nil {
+		makeErrorResponse(w, http.StatusBadRequest, "invalid language id")
+		return
+	}
+	const maxDays = 366
+	days, err := intParam(params.Get("days"), 30)
+	if err != nil || days < 1 || days > maxDays {
+		makeErrorResponse(w, http.StatusBadRequest,
+			"days must be between 1 and "+strconv.Itoa(maxDays))
+		return
+	}
+
+	if _, err = u.db.GetUser(id); err != nil {
+		makeErrorResponse(w, http.StatusNotFound, "user not found")
+		return
+	}
+	stats, err := u.db.GetStats(id, languageId, days)
+	if err != nil {
+		log.Println(err)
+		makeErrorResponse(w, http.StatusInternalServerError,
+			"could not compute stats")
+		return
 	}
+	makeJsonResponse(w, http.StatusOK, stats)
 }
@@ -0,0 +1,145 @@
+package controller
+
+import (
+	"encoding/json"
+	"mutably/api/model"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// VerbForms is a Controller that handles the /verb-forms resource. Anyone
+// can read verb forms, but only admins can edit them.
+type VerbForms struct {
+	db   model.Database
+	auth *model.AuthLayer
+}
+
+func (vf *VerbForms) Routes() []Route {
+	return []Route{
+		{ // GET /v1/verb-forms/{id:[0-9]+}
+			Version:     "v1",
+			Path:        "/verb-forms/{id:[0-9]+}",
+			Method:      "GET",
+			Handler:     vf.getVerbForm,
+			IsProtected: false,
+		},
+		{ // POST /v1/verb-forms
+			Version: "v1",
+			Path:    "/verb-forms",
+			Method:  "POST",
+			Handler: vf.createVerbForm,
+			Role:    model.RoleAdmin,
+		},
+		{ // PUT /v1/verb-forms/{id:[0-9]+}
+			Version: "v1",
+			Path:    "/verb-forms/{id:[0-9]+}",
+			Method:  "PUT",
+			Handler: vf.updateVerbForm,
+			Role:    model.RoleAdmin,
+		},
+		{ // DELETE /v1/verb-forms/{id:[0-9]+}
+			Version: "v1",
+			Path:    "/verb-forms/{id:[0-9]+}",
+			Method:  "DELETE",
+			Handler: vf.deleteVerbForm,
+			Role:    model.RoleAdmin,
+		},
+	}
+}
+
+// decodeVerbForm reads a verb form from the body of r. If that fails, it
+// responds with an error and returns nil.
+func decodeVerbForm(w http.ResponseWriter, r *http.Request) *model.VerbForm {
+	var form model.VerbForm
+	if err := json.NewDecoder(r.Body).Decode(&form); err != nil ||
+		form.Word == "" || form.Infinitive == "" || form.Tense == "" {
+		makeErrorResponse(w, http.StatusBadRequest,
+			"expected a verb form with a word, infinitive, tense, and number")
+		return nil
+	}
+	return &form
+}
+
+// GET /api/v1/verb-forms/{id:[0-9]+}
+func (vf *VerbForms) getVerbForm(w http.ResponseWriter, r *http.Request) {
+	formId, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		makeErrorResponse(w, http.StatusBadRequest, "invalid verb form id")
+		return
+	}
+
+	form, err := vf.db.GetVerbForm(formId)
+	if err != nil {
+		makeErrorResponse(w, http.StatusNotFound, "verb form not found")
+	} else {
+		makeJsonResponse(w, http.StatusOK, form)
+	}
+}
+
+// POST /api/v1/verb-forms
+// {"language": 1, "word": "kreeg", "infinitive": "krijgen", "tense": "past",
+// "mood": "indicative", "persons": [1, 2, 3], "number": "singular"}
+func (vf *VerbForms) createVerbForm(w http.ResponseWriter, r *http.Request) {
+	editorId, err := requestUserId(vf.auth, r)
+	if err != nil {
+		makeErrorResponse(w, http.StatusForbidden, err.Error())
+		return
+	}
+
+	form := decodeVerbForm(w, r)
+	if form == nil {
+		return
+	}
+
+	created, err := vf.db.CreateVerbForm(editorId, form)
+	if err == nil {
+		w.Header().Set("Location",
+			"/api/v1/verb-forms/"+strconv.Itoa(created.Id))
+	}
+	respondWithEdit(w, http.StatusCreated, created, err, "verb form")
+}
+
+// PUT /api/v1/verb-forms/{id:[0-9]+}
+// {"language": 1, "word": "kreeg", "infinitive": "krijgen", "tense": "past",
+// "mood": "indicative", "persons": [1, 2, 3], "number": "singular"}
+func (vf *VerbForms) updateVerbForm(w http.ResponseWriter, r *http.Request) {
+	editorId, err := requestUserId(vf.auth, r)
+	if err != nil {
+		makeErrorResponse(w, http.StatusForbidden, err.Error())
+		return
+	}
+
+	formId, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		makeErrorResponse(w, http.StatusBadRequest, "invalid verb form id")
+		return
+	}
+
+	form := decodeVerbForm(w, r)
+	if form == nil {
+		return
+	}
+
+	updated, err := vf.db.UpdateVerbForm(editorId, formId, form)
+	respondWithEdit(w, http.StatusOK, updated, err, "verb form")
+}
+
+// DELETE /api/v1/verb-forms/{id:[0-9]+}
+func (vf *VerbForms) deleteVerbForm(w http.ResponseWriter, r *http.Request) {
+	editorId, err := requestUserId(vf.auth, r)
+	if err != nil {
+		makeErrorResponse(w, http.StatusForbidden, err.Error())
+		return
+	}
+
+	formId, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		makeErrorResponse(w, http.StatusBadRequest, "invalid verb form id")
+		return
+	}
+
+	err = vf.db.DeleteVerbForm(editorId, formId)
+	respondWithEdit(w, http.StatusNoContent, nil, err, "verb form")
+}
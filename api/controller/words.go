@@ -1,16 +1,21 @@
 package controller
 
 import (
+	"encoding/json"
+	"log"
 	"mutably/api/model"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gorilla/mux"
 )
 
-// Words is a Controller that handles the /words resource.
+// Words is a Controller that handles the /words resource. Only admins can
+// edit words.
 type Words struct {
-	db model.Database
+	db   model.Database
+	auth *model.AuthLayer
 }
 
 func (w *Words) Routes() []Route {
@@ -29,9 +34,65 @@ func (w *Words) Routes() []Route {
 			Handler:     w.getWord,
 			IsProtected: false,
 		},
-		{ // GET /v1/words/{word}/inflections
+		{ // POST /v1/words
+			Version: "v1",
+			Path:    "/words",
+			Method:  "POST",
+			Handler: w.createWord,
+			Role:    model.RoleAdmin,
+		},
+		{ // PUT /v1/words/{id:[0-9]+}
+			Version: "v1",
+			Path:    "/words/{id:[0-9]+}",
+			Method:  "PUT",
+			Handler: w.updateWord,
+			Role:    model.RoleAdmin,
+		},
+		{ // DELETE /v1/words/{id:[0-9]+}
+			Version: "v1",
+			Path:    "/words/{id:[0-9]+}",
+			Method:  "DELETE",
+			Handler: w.deleteWord,
+			Role:    model.RoleAdmin,
+		},
+		{ // GET /v1/words/{id:[0-9]+}/history
+			Version: "v1",
+			Path:    "/words/{id:[0-9]+}/history",
+			Method:  "GET",
+			Handler: w.getWordHistory,
+			Role:    model.RoleAdmin,
+		},
+		{ // GET /v1/words/{word}/analyses
 			Version:     "v1",
-			Path:        "/words/{word}/inflections",
+			Path:        "/words/{word}/analyses",
+			Method:      "GET",
+			Handler:     w.getAnalyses,
+			IsProtected: false,
+		},
+		{ // GET /v1/words/{word}/suggestions
+			Version:     "v1",
+			Path:        "/words/{word}/suggestions",
+			Method:      "GET",
+			Handler:     w.getSuggestions,
+			IsProtected: false,
+		},
+		{ // GET /v1/languages/{id:[0-9]+}/words
+			Version:     "v1",
+			Path:        "/languages/{id:[0-9]+}/words",
+			Method:      "GET",
+			Handler:     w.getLanguageWords,
+			IsProtected: false,
+		},
+		{ // GET /v1/languages/{id:[0-9]+}/words/{word}
+			Version:     "v1",
+			Path:        "/languages/{id:[0-9]+}/words/{word}",
+			Method:      "GET",
+			Handler:     w.getLanguageWord,
+			IsProtected: false,
+		},
+		{ // GET /v1/languages/{id:[0-9]+}/words/{word}/inflections
+			Version:     "v1",
+			Path:        "/languages/{id:[0-9]+}/words/{word}/inflections",
 			Method:      "GET",
 			Handler:     w.getInflections,
 			IsProtected: false,
@@ -39,10 +100,37 @@ func (w *Words) Routes() []Route {
 	}
 }
 
-// GET /api/v1/words
+// GET /api/v1/words?q=een&language=1&limit=20&cursor=MjA&sort=-text
 func (ws *Words) getWords(w http.ResponseWriter, r *http.Request) {
-	words, err := ws.db.GetWords()
-	respondWithAggregate(w, words, len(words), err, "words")
+	query := r.URL.Query().Get("q")
+
+	// Search results are ranked, so they can't be sorted another way.
+	fields := model.WordSortFields
+	if query != "" {
+		fields = model.SortFields{}
+	}
+	opts, err := parseListOptions(r, fields)
+	if err != nil {
+		makeErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var words []*model.Word
+	if query == "" {
+		words, err = ws.db.GetWords(peek(opts))
+	} else {
+		words, err = ws.db.SearchWords(query, peek(opts))
+	}
+	respondWithPage(w, r, words, opts, err, "words")
+}
+
+// intParam converts a query parameter to an int. An empty value is given the
+// default value def.
+func intParam(value string, def int) (int, error) {
+	if value == "" {
+		return def, nil
+	}
+	return strconv.Atoi(value)
 }
 
 // GET /api/v1/words/{id:[0-9]+}
@@ -61,13 +149,229 @@ func (ws *Words) getWord(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// GET /api/v1/words/{word}/inflections
+// POST /api/v1/words
+// {"language": 1, "text": "krijgen"}
+func (ws *Words) createWord(w http.ResponseWriter, r *http.Request) {
+	editorId, err := requestUserId(ws.auth, r)
+	if err != nil {
+		makeErrorResponse(w, http.StatusForbidden, err.Error())
+		return
+	}
+
+	var word model.Word
+	if err = json.NewDecoder(r.Body).Decode(&word); err != nil ||
+		word.Text == "" {
+		makeErrorResponse(w, http.StatusBadRequest,
+			`expected a body of the form {"language": 1, "text": "..."}`)
+		return
+	}
+
+	created, err := ws.db.CreateWord(editorId, word.LanguageId, word.Text)
+	if err == nil {
+		w.Header().Set("Location", "/api/v1/words/"+strconv.Itoa(created.Id))
+	}
+	respondWithEdit(w, http.StatusCreated, created, err, "word")
+}
+
+// PUT /api/v1/words/{id:[0-9]+}
+// {"text": "krijgen"}
+func (ws *Words) updateWord(w http.ResponseWriter, r *http.Request) {
+	editorId, err := requestUserId(ws.auth, r)
+	if err != nil {
+		makeErrorResponse(w, http.StatusForbidden, err.Error())
+		return
+	}
+
+	wordId, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		makeErrorResponse(w, http.StatusBadRequest, "invalid word id")
+		return
+	}
+
+	var body struct {
+		Text string `json:"text"`
+	}
+	if err = json.NewDecoder(r.Body).Decode(&body); err != nil ||
+		body.Text == "" {
+		makeErrorResponse(w, http.StatusBadRequest,
+			`expected a body of the form {"text": "..."}`)
+		return
+	}
+
+	word, err := ws.db.UpdateWord(editorId, wordId, body.Text)
+	respondWithEdit(w, http.StatusOK, word, err, "word")
+}
+
+// DELETE /api/v1/words/{id:[0-9]+}
+func (ws *Words) deleteWord(w http.ResponseWriter, r *http.Request) {
+	editorId, err := requestUserId(ws.auth, r)
+	if err != nil {
+		makeErrorResponse(w, http.StatusForbidden, err.Error())
+		return
+	}
+
+	wordId, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		makeErrorResponse(w, http.StatusBadRequest, "invalid word id")
+		return
+	}
+
+	err = ws.db.DeleteWord(editorId, wordId)
+	respondWithEdit(w, http.StatusNoContent, nil, err, "word")
+}
+
+// GET /api/v1/words/{id:[0-9]+}/history?limit=20&cursor=MjA&sort=-changed
+func (ws *Words) getWordHistory(w http.ResponseWriter, r *http.Request) {
+	wordId, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		makeErrorResponse(w, http.StatusBadRequest, "invalid word id")
+		return
+	}
+
+	opts, err := parseListOptions(r, model.AuditSortFields)
+	if err != nil {
+		makeErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	entries, err := ws.db.GetWordHistory(wordId, peek(opts))
+	respondWithPage(w, r, entries, opts, err, "changes")
+}
+
+// GET /api/v1/languages/{id:[0-9]+}/words?limit=20&cursor=MjA&sort=-text
+func (ws *Words) getLanguageWords(w http.ResponseWriter, r *http.Request) {
+	languageId, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		makeErrorResponse(w, http.StatusBadRequest, "invalid language id")
+		return
+	}
+
+	opts, err := parseListOptions(r, model.WordSortFields)
+	if err != nil {
+		makeErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	opts.LanguageId = languageId
+
+	words, err := ws.db.GetWords(peek(opts))
+	respondWithPage(w, r, words, opts, err, "words")
+}
+
+// GET /api/v1/languages/{id:[0-9]+}/words/{word}
+func (ws *Words) getLanguageWord(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	languageId, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		makeErrorResponse(w, http.StatusBadRequest, "invalid language id")
+		return
+	}
+
+	word, err := ws.db.FindWord(languageId, vars["word"])
+	if err != nil {
+		makeErrorResponse(w, http.StatusNotFound, "word not found")
+	} else {
+		makeJsonResponse(w, http.StatusOK, word)
+	}
+}
+
+// GET /api/v1/languages/{id:[0-9]+}/words/{word}/inflections?tenses=perfect,future
 func (ws *Words) getInflections(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
-	table, err := ws.db.GetConjugationTable(vars["word"])
+	languageId, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		makeErrorResponse(w, http.StatusBadRequest, "invalid language id")
+		return
+	}
+
+	compounds, err := parseCompoundTenses(r.URL.Query().Get("tenses"))
+	if err != nil {
+		makeErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	table, err := ws.db.GetConjugationTable(languageId, vars["word"], compounds)
 	if err != nil {
-		makeErrorResponse(w, http.StatusNotFound, err.Error())
+		// The word may have been misspelled, so point to ones that exist.
+		suggestions, suggestErr := ws.db.SuggestWords(languageId, vars["word"],
+			defaultSuggestions)
+		if suggestErr != nil {
+			log.Println(suggestErr)
+		}
+		makeJsonResponse(w, http.StatusNotFound, map[string]interface{}{
+			"error":       err.Error(),
+			"suggestions": suggestions,
+		})
 		return
 	}
 	makeJsonResponse(w, http.StatusOK, table)
 }
+
+// GET /api/v1/words/{word}/analyses?language=1
+func (ws *Words) getAnalyses(w http.ResponseWriter, r *http.Request) {
+	languageId, err := intParam(r.URL.Query().Get("language"), 0)
+	if err != nil || languageId < 0 {
+		makeErrorResponse(w, http.StatusBadRequest, "invalid language id")
+		return
+	}
+
+	word := mux.Vars(r)["word"]
+	analyses, err := ws.db.AnalyzeWord(languageId, word)
+	if err != nil {
+		log.Println(err)
+	}
+	if len(analyses) == 0 {
+		suggestions, suggestErr := ws.db.SuggestWords(languageId, word,
+			defaultSuggestions)
+		if suggestErr != nil {
+			log.Println(suggestErr)
+		}
+		makeJsonResponse(w, http.StatusNotFound, map[string]interface{}{
+			"error":       "word " + word + " is not a known verb form",
+			"suggestions": suggestions,
+		})
+		return
+	}
+	makeJsonResponse(w, http.StatusOK, analyses)
+}
+
+// The number of suggestions to give for a word that was not found
+const defaultSuggestions = 5
+
+// GET /api/v1/words/{word}/suggestions?language=1&limit=5
+func (ws *Words) getSuggestions(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	languageId, err := intParam(params.Get("language"), 0)
+	if err != nil || languageId < 0 {
+		makeErrorResponse(w, http.StatusBadRequest, "invalid language id")
+		return
+	}
+
+	const maxLimit = 20
+	limit, err := intParam(params.Get("limit"), defaultSuggestions)
+	if err != nil || limit < 1 || limit > maxLimit {
+		makeErrorResponse(w, http.StatusBadRequest,
+			"limit must be between 1 and "+strconv.Itoa(maxLimit))
+		return
+	}
+
+	suggestions, err := ws.db.SuggestWords(languageId, mux.Vars(r)["word"], limit)
+	respondWithAggregate(w, suggestions, len(suggestions), err, "suggestions")
+}
+
+// parseCompoundTenses converts a comma-separated list of tense names into
+// the compound tenses that they represent.
+func parseCompoundTenses(list string) ([]model.CompoundTense, error) {
+	var compounds []model.CompoundTense
+	if list == "" {
+		return compounds, nil
+	}
+
+	for _, name := range strings.Split(list, ",") {
+		compound, err := model.ParseCompoundTense(strings.TrimSpace(name))
+		if err != nil {
+			return nil, err
+		}
+		compounds = append(compounds, compound)
+	}
+	return compounds, nil
+}
@@ -0,0 +1,141 @@
+package model
+
+import "database/sql"
+
+// Analysis is one way to read a word as a form of a verb. Finite readings
+// have a tense, mood, and number; non-finite ones only name their form.
+type Analysis struct {
+	// The word that was analyzed
+	Word string `json:"word"`
+
+	// The id of the language that the reading belongs to
+	LanguageId int `json:"language"`
+
+	// The infinitive that the word is a form of
+	Infinitive string `json:"infinitive"`
+
+	// The grammatical tense (e.g., 'past') of a finite reading
+	Tense string `json:"tense,omitempty"`
+
+	// The grammatical mood (e.g., 'indicative') of a finite reading
+	Mood string `json:"mood,omitempty"`
+
+	// The grammatical person (1, 2, or 3) of a singular reading
+	Person int `json:"person,omitempty"`
+
+	// 'singular' or 'plural' for a finite reading
+	Number string `json:"number,omitempty"`
+
+	// The form (e.g., 'past participle') of a non-finite reading
+	NonFinite string `json:"nonFinite,omitempty"`
+}
+
+// AnalyzeWord returns every reading of word as a verb form. A word may be
+// ambiguous within a verb (e.g., 'kreeg' is the first, second, and third
+// person), across verbs, and across languages. If languageId is not 0, only
+// readings in that language are returned.
+func (db *PsqlDB) AnalyzeWord(languageId int, word string) ([]*Analysis,
+	error) {
+	analyses, err := db.analyzeFiniteForms(languageId, word)
+	if err != nil {
+		return nil, err
+	}
+	nonFinite, err := db.analyzeNonFiniteForms(languageId, word)
+	return append(analyses, nonFinite...), err
+}
+
+// analyzeFiniteForms returns the readings of word found in verb_forms.
+// A form shared by several persons is given one reading per person.
+func (db *PsqlDB) analyzeFiniteForms(languageId int, word string) ([]*Analysis,
+	error) {
+	rows, err := db.Query(`
+		SELECT verb_forms.lang_id, inf.word, tenses.tense, moods.mood, num,
+		       person
+		FROM verb_forms
+		JOIN words form on form.id = verb_forms.word_id
+		JOIN words inf  on inf.id  = verb_forms.inf_id
+		JOIN tenses     on tenses.id = verb_forms.tense_id
+		JOIN moods      on moods.id  = verb_forms.mood_id
+		WHERE form.word = $1
+		AND   ($2 = 0 OR verb_forms.lang_id = $2)
+		ORDER BY verb_forms.lang_id, inf.word, verb_forms.tense_id,
+		         verb_forms.mood_id, num, person`,
+		word, languageId,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var analyses []*Analysis
+	for rows.Next() {
+		reading := Analysis{Word: word}
+		var number Number
+		var person sql.NullInt64
+		err = rows.Scan(&reading.LanguageId, &reading.Infinitive,
+			&reading.Tense, &reading.Mood, &number, &person)
+		if err != nil {
+			return nil, err
+		}
+
+		if number != Singular {
+			reading.Number = "plural"
+			analyses = append(analyses, &reading)
+			continue
+		}
+		reading.Number = "singular"
+		for i, p := range []Person{First, Second, Third} {
+			if Person(person.Int64)&p != 0 {
+				singular := reading
+				singular.Person = i + 1
+				analyses = append(analyses, &singular)
+			}
+		}
+	}
+	return analyses, rows.Err()
+}
+
+// analyzeNonFiniteForms returns the readings of word as an infinitive,
+// participle, or gerund.
+func (db *PsqlDB) analyzeNonFiniteForms(languageId int,
+	word string) ([]*Analysis, error) {
+	rows, err := db.Query(`
+		SELECT forms.lang_id, inf.word, forms.name
+		FROM (
+		  SELECT lang_id, word_id, word_id AS form_id, 1 AS rank,
+		         'infinitive' AS name
+		  FROM infinitives
+		  UNION ALL
+		  SELECT lang_id, word_id, pres_ptc_id, 2, 'present participle'
+		  FROM infinitives
+		  UNION ALL
+		  SELECT lang_id, word_id, past_ptc_id, 3, 'past participle'
+		  FROM infinitives
+		  UNION ALL
+		  SELECT lang_id, word_id, gerund_id, 4, 'gerund'
+		  FROM infinitives
+		) AS forms
+		JOIN words form on form.id = forms.form_id
+		JOIN words inf  on inf.id  = forms.word_id
+		WHERE form.word = $1
+		AND   ($2 = 0 OR forms.lang_id = $2)
+		ORDER BY forms.lang_id, inf.word, forms.rank`,
+		word, languageId,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var analyses []*Analysis
+	for rows.Next() {
+		reading := &Analysis{Word: word}
+		err = rows.Scan(&reading.LanguageId, &reading.Infinitive,
+			&reading.NonFinite)
+		if err != nil {
+			return nil, err
+		}
+		analyses = append(analyses, reading)
+	}
+	return analyses, rows.Err()
+}
@@ -1,7 +1,11 @@
 package model
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"log"
@@ -14,62 +18,144 @@ import (
 	"github.com/dgrijalva/jwt-go"
 )
 
+// ErrRevokedToken is returned for access tokens that were revoked.
+var ErrRevokedToken = errors.New("token was revoked")
+
+// How long access and refresh tokens last
+const (
+	accessTokenLifetime  = time.Hour
+	refreshTokenLifetime = 30 * 24 * time.Hour
+)
+
 // AuthLayer handles resource authorization.
 //
 // The API uses JSON Web Tokens to grant access to protected resources. Those
 // tokens need to be generated and/or validated before changes to the system
 // can be considered. AuthLayer encapsulates those operations and signs the
-// tokens using a private key. See NewAuthLayer() for setup details.
+// tokens using private keys. See NewAuthLayer() for setup details.
+//
+// Access tokens are short-lived. Clients trade refresh tokens for new ones
+// instead of resending credentials, and both kinds can be revoked through
+// the TokenStore.
 type AuthLayer struct {
-	PrivateKey string
-	keyFunc    jwt.Keyfunc
+	keys       *keySet
+	store      TokenStore
 	middleware *jwtmiddleware.JWTMiddleware
 }
 
 // Creates and returns a new AuthLayer instance.
-// AuthLayer relies on a private key to sign tokens. That key should be defined
-// in the environment variable API_PRIVATE_KEY before calling this function.
-func NewAuthLayer() *AuthLayer {
-	privateKey := os.Getenv("API_PRIVATE_KEY")
-	if privateKey == "" {
-		log.Fatal("Environment variable API_PRIVATE_KEY should not be empty")
-	}
-
-	auth := &AuthLayer{PrivateKey: privateKey}
-	auth.keyFunc = func(token *jwt.Token) (interface{}, error) {
-		return []byte(auth.PrivateKey), nil
+// AuthLayer signs tokens with the RSA or Ed25519 keys in the directory named
+// by the environment variable API_KEY_DIR; each file <kid>.pem holds one.
+// Other services can verify the tokens with PublicKeys(). The newest key
+// signs, and keys can be rotated without downtime; see keySet for details.
+//
+// Without a key directory, tokens are signed with the HS256 secret in the
+// environment variable API_PRIVATE_KEY. If both are defined, the secret
+// only verifies the tokens that it signed before the keys were added.
+// store keeps track of refresh tokens and revocations.
+func NewAuthLayer(store TokenStore) *AuthLayer {
+	keys, err := newKeySet(os.Getenv("API_KEY_DIR"),
+		os.Getenv("API_PRIVATE_KEY"))
+	if err != nil {
+		log.Fatal("Could not load signing keys from API_KEY_DIR or "+
+			"API_PRIVATE_KEY; ", err)
 	}
 
+	auth := &AuthLayer{keys: keys, store: store}
+	// The key set checks the algorithm of each token against its key.
 	auth.middleware = jwtmiddleware.New(jwtmiddleware.Options{
-		ValidationKeyGetter: auth.keyFunc,
-		SigningMethod:       jwt.SigningMethodHS256,
+		ValidationKeyGetter: keys.verifier,
 	})
 	return auth
 }
 
-// GenerateToken creates a one-hour jwt token signed with auth.PrivateKey.
-func (auth *AuthLayer) GenerateToken(w http.ResponseWriter) {
-	auth.GenerateTokenWithClaim(w, nil)
+// Tokens are what a client receives when it signs in.
+type Tokens struct {
+	// A jwt that authorizes requests for ExpiresIn seconds
+	Token     string `json:"token"`
+	ExpiresIn int    `json:"expiresIn"`
+
+	// An opaque token that can be traded once for new Tokens
+	RefreshToken string `json:"refreshToken"`
 }
 
-// GenerateTokenWithClaim creates a one-hour jwt token that is signed with
-// auth.PrivateKey and contains a set of custom claims.
-// If no additional claims are required, use AuthLayer.GenerateToken() instead.
-func (auth *AuthLayer) GenerateTokenWithClaim(w http.ResponseWriter,
-	customClaims map[string]interface{}) {
-	token := jwt.New(jwt.SigningMethodHS256)
-	claims := token.Claims.(jwt.MapClaims)
+// IssueTokens creates a one-hour jwt token for a user, signed with the
+// current key, along with a refresh token that lasts 30 days.
+func (auth *AuthLayer) IssueTokens(userId string) (*Tokens, error) {
+	version, err := auth.store.GetTokenVersion(userId)
+	if err != nil {
+		return nil, err
+	}
+	role, err := auth.store.GetUserRole(userId)
+	if err != nil {
+		return nil, err
+	}
 
+	var token *jwt.Token
+	var signingKey interface{}
+	if key := auth.keys.signer(); key != nil {
+		token = jwt.New(key.method)
+		token.Header["kid"] = key.id
+		signingKey = key.privateKey
+	} else {
+		token = jwt.New(jwt.SigningMethodHS256)
+		signingKey = auth.keys.secret
+	}
+	claims := token.Claims.(jwt.MapClaims)
 	claims["iss"] = "mutably"
-	claims["exp"] = time.Now().Add(time.Hour).Unix()
-	if customClaims != nil {
-		for attr, claim := range customClaims {
-			claims[attr] = claim
-		}
+	claims["exp"] = time.Now().Add(accessTokenLifetime).Unix()
+	claims["id"] = userId
+	claims["ver"] = version
+	claims["role"] = role
+
+	tokens := &Tokens{ExpiresIn: int(accessTokenLifetime / time.Second)}
+	tokens.Token, err = token.SignedString(signingKey)
+	if err != nil {
+		return nil, err
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.Write(auth.sign(token))
+	secret := make([]byte, 32)
+	if _, err = rand.Read(secret); err != nil {
+		return nil, err
+	}
+	tokens.RefreshToken = base64.RawURLEncoding.EncodeToString(secret)
+	err = auth.store.CreateRefreshToken(userId, hashToken(tokens.RefreshToken),
+		time.Now().Add(refreshTokenLifetime))
+	if err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// PublicKeys returns the keys that verify access tokens, in JWKS form. It
+// is empty if tokens are signed with a secret.
+func (auth *AuthLayer) PublicKeys() *JWKSet {
+	return auth.keys.publicKeys()
+}
+
+// Refresh trades a refresh token for new tokens. The old refresh token
+// can't be used again. ErrInvalidRefreshToken is returned if it isn't valid.
+func (auth *AuthLayer) Refresh(refreshToken string) (*Tokens, error) {
+	userId, err := auth.store.UseRefreshToken(hashToken(refreshToken))
+	if err != nil {
+		return nil, err
+	}
+	return auth.IssueTokens(userId)
+}
+
+// Revoke signs a user out. If refreshToken is empty, every token of the
+// user is revoked; otherwise only that refresh token is.
+func (auth *AuthLayer) Revoke(userId, refreshToken string) error {
+	if refreshToken == "" {
+		return auth.store.RevokeTokens(userId)
+	}
+	return auth.store.RevokeRefreshToken(userId, hashToken(refreshToken))
+}
+
+// hashToken returns the form of a refresh token that is stored.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
 
 // GetClaims returns the claims from a token in the Authorization header.
@@ -80,7 +166,7 @@ func (auth *AuthLayer) GetClaims(r *http.Request) (jwt.MapClaims, error) {
 		return nil, errors.New("bad token header")
 	}
 
-	token, err := jwt.Parse(header[1], auth.keyFunc)
+	token, err := jwt.Parse(header[1], auth.keys.verifier)
 	if err != nil {
 		return nil, err
 	}
@@ -89,9 +175,84 @@ func (auth *AuthLayer) GetClaims(r *http.Request) (jwt.MapClaims, error) {
 }
 
 // Authenticate returns a middleware handler that will use jwt to validate the
-// Authorization header of a *http.Request.
+// Authorization header of a *http.Request. Tokens that were revoked are
+// rejected.
 func (auth *AuthLayer) Authenticate(handler http.HandlerFunc) http.HandlerFunc {
-	return auth.middleware.Handler(handler).(http.HandlerFunc)
+	return auth.middleware.Handler(auth.rejectRevoked(handler)).(http.HandlerFunc)
+}
+
+// RequireRole returns a handler that responds with 403 Forbidden unless the
+// token of a request claims a role that includes role. It should only wrap
+// handlers that Authenticate has validated.
+func (auth *AuthLayer) RequireRole(role Role,
+	handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, err := auth.GetClaims(r)
+		if err != nil || !ClaimedRole(claims).Includes(role) {
+			writeError(w, http.StatusForbidden,
+				"resource requires "+string(role)+" privileges")
+			return
+		}
+		handler(w, r)
+	}
+}
+
+// ClaimedRole returns the role that the claims of an access token grant.
+// Role changes revoke access tokens, so the claim can be trusted.
+func ClaimedRole(claims jwt.MapClaims) Role {
+	role, _ := claims["role"].(string)
+	return Role(role)
+}
+
+// UserId returns the id of the user whose token is in the Authorization
+// header of r. Unlike GetClaims, it returns ErrRevokedToken for tokens that
+// were revoked.
+func (auth *AuthLayer) UserId(r *http.Request) (string, error) {
+	claims, err := auth.GetClaims(r)
+	if err == nil {
+		err = auth.checkVersion(claims)
+	}
+	if err != nil {
+		return "", err
+	}
+	userId, _ := claims["id"].(string)
+	return userId, nil
+}
+
+// rejectRevoked responds with 401 Unauthorized to requests whose token was
+// issued under an older token version than its user has now.
+func (auth *AuthLayer) rejectRevoked(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, err := auth.UserId(r); err != nil {
+			writeError(w, http.StatusUnauthorized, err.Error())
+			return
+		}
+		handler(w, r)
+	}
+}
+
+// writeError responds with a JSON error message, as the controllers do.
+func writeError(w http.ResponseWriter, code int, message string) {
+	resp, _ := json.Marshal(map[string]string{"error": message})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(resp)
+}
+
+// checkVersion returns ErrRevokedToken if the claims of an access token
+// aren't for the current token version of their user.
+func (auth *AuthLayer) checkVersion(claims jwt.MapClaims) error {
+	userId, _ := claims["id"].(string)
+	version, _ := claims["ver"].(float64)
+
+	current, err := auth.store.GetTokenVersion(userId)
+	if err != nil && err != sql.ErrNoRows {
+		log.Println(err)
+	}
+	if err != nil || int(version) != current {
+		return ErrRevokedToken
+	}
+	return nil
 }
 
 // GetCredentials reads a set of username:password credentials from a base64
@@ -118,10 +279,3 @@ func (auth *AuthLayer) GetCredentials(r *http.Request) (string, string, error) {
 
 	return credentials[0], credentials[1], nil
 }
-
-func (auth *AuthLayer) sign(token *jwt.Token) []byte {
-	signedToken, _ := token.SignedString([]byte(auth.PrivateKey))
-	jsonBody := map[string]string{"token": signedToken}
-	resp, _ := json.Marshal(jsonBody)
-	return resp
-}
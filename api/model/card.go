@@ -0,0 +1,247 @@
+package model
+
+import (
+	"database/sql"
+	"mutably/api/model/scheduler"
+	"time"
+)
+
+// Card is a cell that a user studies with spaced repetition.
+type Card struct {
+	Id int `json:"id"`
+	FormCell
+
+	// The scheduling state; see scheduler.Card
+	Repetitions int     `json:"repetitions"`
+	Interval    int     `json:"interval"`
+	Ease        float64 `json:"ease"`
+	Lapses      int     `json:"lapses"`
+
+	Due        time.Time  `json:"due"`
+	ReviewedAt *time.Time `json:"reviewedAt,omitempty"`
+
+	// The forms that fill the cell, so that clients can show the answer
+	Forms []string `json:"forms"`
+}
+
+// cardColumns selects the fields of a Card.
+const cardColumns = `
+	SELECT t.id, t.repetitions, t.interval_days, t.ease, t.lapses, t.due_at,
+	       t.reviewed_at,` + cellColumns + `
+	FROM review_cards t` + cellJoins
+
+// scanCard reads a row selected with cardColumns.
+func scanCard(row interface {
+	Scan(...interface{}) error
+}) (*Card, error) {
+	c := &Card{}
+	dest := append([]interface{}{&c.Id, &c.Repetitions, &c.Interval, &c.Ease,
+		&c.Lapses, &c.Due, &c.ReviewedAt}, c.scanDest()...)
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+	c.finish()
+	return c, nil
+}
+
+// GetDueCards returns up to limit cards of a user that are due, most overdue
+// first. Only cards in the user's target language are returned. If too few
+// are due, cells that the user hasn't studied are added as new cards, but
+// no more than newPerDay are added each day. A user who has no target
+// language gets ErrNoTargetLanguage.
+func (db *PsqlDB) GetDueCards(userId string, limit,
+	newPerDay int) ([]*Card, error) {
+	var languageId sql.NullInt64
+	err := db.QueryRow(`SELECT target_language_id FROM users WHERE id = $1`,
+		userId).Scan(&languageId)
+	if err != nil {
+		return nil, err
+	}
+	if !languageId.Valid {
+		return nil, ErrNoTargetLanguage
+	}
+
+	cards, err := db.queryDueCards(userId, int(languageId.Int64), limit)
+	if err != nil || len(cards) == limit {
+		return cards, err
+	}
+
+	var addedToday int
+	err = db.QueryRow(`
+		SELECT count(*) FROM review_cards
+		WHERE user_id = $1 AND created_at >= date_trunc('day', LOCALTIMESTAMP)`,
+		userId,
+	).Scan(&addedToday)
+	if err != nil {
+		return nil, err
+	}
+
+	count := limit - len(cards)
+	if newPerDay-addedToday < count {
+		count = newPerDay - addedToday
+	}
+	if count <= 0 {
+		return cards, nil
+	}
+	if err = db.addCards(userId, int(languageId.Int64), count); err != nil {
+		return nil, err
+	}
+	return db.queryDueCards(userId, int(languageId.Int64), limit)
+}
+
+// queryDueCards returns up to limit cards of a user in a language that are
+// due, along with their forms.
+func (db *PsqlDB) queryDueCards(userId string, languageId,
+	limit int) ([]*Card, error) {
+	rows, err := db.Query(cardColumns+`
+		WHERE t.user_id = $1
+		AND   t.lang_id = $2
+		AND   t.due_at <= LOCALTIMESTAMP
+		ORDER BY t.due_at, t.id
+		LIMIT $3`,
+		userId, languageId, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var cards []*Card
+	for rows.Next() {
+		c, err := scanCard(rows)
+		if err != nil {
+			return nil, err
+		}
+		cards = append(cards, c)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, c := range cards {
+		if c.Forms, err = db.getCellForms(&c.FormCell); err != nil {
+			return nil, err
+		}
+	}
+	return cards, nil
+}
+
+// newCells selects the cells of the verb forms of language $2 that user $1
+// has no cards for. A singular form that is shared by several persons is a
+// cell for each of them. Callers add a condition on vf.id.
+const newCells = `
+	SELECT vf.lang_id, vf.inf_id, vf.tense_id, vf.mood_id, p.person, vf.num
+	FROM verb_forms vf,
+	  unnest(CASE WHEN vf.num = 1 THEN ARRAY[2, 4, 8]
+	              ELSE ARRAY[NULL::int] END) AS p (person)
+	WHERE vf.lang_id = $2
+	AND   (vf.num != 1 OR vf.person & p.person != 0)
+	AND   NOT EXISTS (
+	  SELECT 1 FROM review_cards c
+	  WHERE c.user_id  = $1
+	  AND   c.inf_id   = vf.inf_id
+	  AND   c.tense_id = vf.tense_id
+	  AND   c.mood_id  = vf.mood_id
+	  AND   COALESCE(c.person, 0) = COALESCE(p.person, 0)
+	  AND   c.num      = vf.num)`
+
+// addCards gives a user up to count new cards for random cells of a
+// language that they don't have cards for. Cells are read in id order from
+// a random form, so only the forms up to the last new card are checked.
+// Cells that several forms share are only added once, so fewer than count
+// cards may be added.
+func (db *PsqlDB) addCards(userId string, languageId, count int) error {
+	_, err := db.Exec(randomStart+`
+		INSERT INTO review_cards (user_id, lang_id, inf_id, tense_id, mood_id,
+		                          person, num)
+		SELECT $1, lang_id, inf_id, tense_id, mood_id, person, num
+		FROM (
+		  (`+newCells+`
+		   AND vf.id >= (SELECT id FROM start)
+		   ORDER BY vf.id)
+		  UNION ALL
+		  (`+newCells+`
+		   AND vf.id < (SELECT id FROM start)
+		   ORDER BY vf.id)
+		) AS cells
+		LIMIT $3
+		ON CONFLICT DO NOTHING`,
+		userId, languageId, count,
+	)
+	return err
+}
+
+// ReviewCard records the grade that a user gave to one of their cards and
+// schedules its next review. sql.ErrNoRows is returned if the user has no
+// such card.
+func (db *PsqlDB) ReviewCard(userId string, id int,
+	grade scheduler.Grade) (*Card, error) {
+	if grade < scheduler.MinGrade || grade > scheduler.MaxGrade {
+		return nil, scheduler.ErrInvalidGrade
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Reviews that arrive together are applied one after the other.
+	c, err := scanCard(tx.QueryRow(cardColumns+`
+		WHERE t.id = $1 AND t.user_id = $2
+		FOR UPDATE OF t`,
+		id, userId,
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	// Times are taken from the database so that they match its defaults.
+	var now time.Time
+	if err = tx.QueryRow(`SELECT LOCALTIMESTAMP`).Scan(&now); err != nil {
+		return nil, err
+	}
+	next, err := scheduler.Review(scheduler.Card{
+		Repetitions: c.Repetitions,
+		Interval:    c.Interval,
+		Ease:        c.Ease,
+		Lapses:      c.Lapses,
+		Due:         c.Due,
+	}, grade, now)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(`
+		UPDATE review_cards
+		SET repetitions = $2, interval_days = $3, ease = $4, lapses = $5,
+		    due_at = $6, reviewed_at = $7
+		WHERE id = $1`,
+		id, next.Repetitions, next.Interval, next.Ease, next.Lapses, next.Due,
+		now,
+	)
+	if err != nil {
+		return nil, err
+	}
+	_, err = tx.Exec(`
+		INSERT INTO reviews (card_id, grade, reviewed_at)
+		VALUES ($1, $2, $3)`,
+		id, grade, now,
+	)
+	if err != nil {
+		return nil, err
+	}
+	err = recordResult(tx, userId, &c.FormCell, grade >= scheduler.PassGrade)
+	if err != nil {
+		return nil, err
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	c.Repetitions, c.Interval, c.Ease = next.Repetitions, next.Interval,
+		next.Ease
+	c.Lapses, c.Due, c.ReviewedAt = next.Lapses, next.Due, &now
+	c.Forms, err = db.getCellForms(&c.FormCell)
+	return c, err
+}
@@ -0,0 +1,154 @@
+package model
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+)
+
+// CompoundTense is a tense that pairs a finite form of an auxiliary verb
+// with a non-finite form of the main verb (e.g., 'heb gekregen').
+type CompoundTense int
+
+const (
+	// The present of the perfect auxiliary and the past participle
+	Perfect CompoundTense = iota
+	// The past of the perfect auxiliary and the past participle
+	Pluperfect
+	// The present of the future auxiliary and the infinitive
+	Future
+	// The past of the future auxiliary and the infinitive
+	Conditional
+)
+
+// ParseCompoundTense converts a tense name (e.g., 'perfect') into a
+// CompoundTense. A non-nil error is returned if the name is unknown.
+func ParseCompoundTense(name string) (CompoundTense, error) {
+	switch strings.ToLower(name) {
+	case "perfect":
+		return Perfect, nil
+	case "pluperfect":
+		return Pluperfect, nil
+	case "future":
+		return Future, nil
+	case "conditional":
+		return Conditional, nil
+	default:
+		return 0, errors.New("unknown tense " + name)
+	}
+}
+
+// Indexes of the moods returned by getMoodInflections
+const (
+	indicativeMood = iota
+	subjunctiveMood
+)
+
+// futureAuxiliary describes how a language builds its future and
+// conditional tenses.
+type futureAuxiliary struct {
+	// The infinitive of the auxiliary (e.g., 'zullen')
+	infinitive string
+	// The mood whose past forms build the conditional. Dutch uses the
+	// indicative ('zou krijgen') and German the subjunctive ('würde kriegen').
+	conditionalMood int
+}
+
+// futureAuxiliaries maps canonical language names to their future auxiliary.
+var futureAuxiliaries = map[string]futureAuxiliary{
+	"dutch":  {infinitive: "zullen", conditionalMood: indicativeMood},
+	"german": {infinitive: "werden", conditionalMood: subjunctiveMood},
+}
+
+// addCompoundTenses builds each tense in compounds for the infinitive in
+// table. table should already contain the infinitive's auxiliary and
+// non-finite forms.
+func (db *PsqlDB) addCompoundTenses(languageId int, table *ConjugationTable,
+	compounds []CompoundTense) error {
+	if len(compounds) == 0 {
+		return nil
+	}
+
+	var language string
+	err := db.QueryRow(`SELECT name FROM languages WHERE id = $1`,
+		languageId).Scan(&language)
+	if err != nil {
+		return err
+	}
+
+	for _, compound := range compounds {
+		auxiliary, nonFinite := table.Auxiliary, table.NonFinite.PastParticiple
+		mood, isPast := indicativeMood, compound == Pluperfect
+
+		if compound == Future || compound == Conditional {
+			future := futureAuxiliaries[language]
+			auxiliary, nonFinite = future.infinitive, table.Infinitive
+			if compound == Conditional {
+				mood, isPast = future.conditionalMood, true
+			}
+		}
+
+		tense, err := db.composeTense(languageId, auxiliary, mood, isPast,
+			nonFinite)
+		if err != nil {
+			return err
+		}
+
+		switch compound {
+		case Perfect:
+			table.Perfect = tense
+		case Pluperfect:
+			table.Pluperfect = tense
+		case Future:
+			table.Future = tense
+		case Conditional:
+			table.Conditional = tense
+		}
+	}
+	return nil
+}
+
+// composeTense pairs each form of auxiliary in a mood and simple tense with
+// a non-finite form. The result is empty if either verb is unknown to the
+// language.
+func (db *PsqlDB) composeTense(languageId int, auxiliary string, mood int,
+	isPast bool, nonFinite string) (*TenseInflection, error) {
+	composed := NewTenseInflection()
+	if auxiliary == "" || nonFinite == "" {
+		return composed, nil
+	}
+
+	var auxId int
+	err := db.QueryRow(`SELECT id FROM words WHERE lang_id = $1 AND word = $2`,
+		languageId, auxiliary).Scan(&auxId)
+	if err == sql.ErrNoRows {
+		return composed, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	moods, err := db.getMoodInflections(auxId)
+	if err != nil {
+		return nil, err
+	}
+	forms := moods[mood].Present
+	if isPast {
+		forms = moods[mood].Past
+	}
+
+	join := func(auxForms []string) []string {
+		joined := make([]string, 0, len(auxForms))
+		for _, form := range auxForms {
+			joined = append(joined, form+" "+nonFinite)
+		}
+		return joined
+	}
+	composed.First = join(forms.First)
+	composed.Second = join(forms.Second)
+	composed.Third = join(forms.Third)
+	composed.Plural = join(forms.Plural)
+	composed.PluralFirst = join(forms.PluralFirst)
+	composed.PluralSecond = join(forms.PluralSecond)
+	composed.PluralThird = join(forms.PluralThird)
+	return composed, nil
+}
@@ -0,0 +1,218 @@
+package model
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+var (
+	// ErrUnrelatedForm is returned when a correction would replace a verb
+	// form that doesn't exist or belongs to another verb.
+	ErrUnrelatedForm = errors.New(
+		"the replaced verb form is not a form of the corrected verb")
+
+	// ErrAlreadyReviewed is returned when a correction is reviewed twice.
+	ErrAlreadyReviewed = errors.New("correction was already reviewed")
+
+	// ErrStaleCorrection is returned when a correction is accepted after
+	// the verb form it replaces was deleted.
+	ErrStaleCorrection = errors.New("the replaced verb form no longer exists")
+)
+
+// The statuses of a correction
+const (
+	CorrectionPending  = "pending"
+	CorrectionAccepted = "accepted"
+	CorrectionRejected = "rejected"
+)
+
+// Correction is a verb form that a user proposes and an admin reviews.
+type Correction struct {
+	Id int `json:"id"`
+
+	// The user who proposed the correction; empty if they were deleted
+	SubmitterId string `json:"submitter,omitempty"`
+
+	// The id of the verb form to replace, or 0 to add Form as a new one
+	Replaces int `json:"replaces,omitempty"`
+
+	// The proposed verb form, or the applied one once accepted
+	Form VerbForm `json:"form"`
+
+	// Why the submitter thinks the form is wrong
+	Comment string `json:"comment,omitempty"`
+
+	// 'pending', 'accepted', or 'rejected'
+	Status string `json:"status"`
+
+	// The admin who reviewed the correction and why they decided as they did
+	ReviewerId string `json:"reviewer,omitempty"`
+	ReviewNote string `json:"reviewNote,omitempty"`
+
+	CreatedAt  time.Time  `json:"createdAt"`
+	ReviewedAt *time.Time `json:"reviewedAt,omitempty"`
+}
+
+// CorrectionSortFields are the fields that corrections can be sorted by.
+var CorrectionSortFields = SortFields{"id": "id", "created": "created_at"}
+
+// correctionColumns selects the fields of a Correction.
+const correctionColumns = `
+	SELECT id, COALESCE(submitter_id::text, ''), COALESCE(replaces_id, 0),
+	       form, comment, status, COALESCE(reviewer_id::text, ''),
+	       review_note, created_at, reviewed_at
+	FROM corrections`
+
+// scanCorrection reads a row selected with correctionColumns.
+func scanCorrection(row interface {
+	Scan(...interface{}) error
+}) (*Correction, error) {
+	c := &Correction{}
+	var form []byte
+	err := row.Scan(&c.Id, &c.SubmitterId, &c.Replaces, &form, &c.Comment,
+		&c.Status, &c.ReviewerId, &c.ReviewNote, &c.CreatedAt, &c.ReviewedAt)
+	if err != nil {
+		return nil, err
+	}
+	return c, json.Unmarshal(form, &c.Form)
+}
+
+// CreateCorrection stores a correction that a user proposes. The form must
+// be one that an admin could create: ErrInvalidForm and ErrUnknownCell are
+// returned otherwise. If c.Replaces is not 0, it must be a form of the same
+// verb, or ErrUnrelatedForm is returned.
+func (db *PsqlDB) CreateCorrection(submitterId string,
+	c *Correction) (*Correction, error) {
+	if _, _, err := c.Form.personAndNumber(); err != nil {
+		return nil, err
+	}
+	infId, _, _, err := findCell(db, &c.Form)
+	if err != nil {
+		return nil, err
+	}
+	if c.Replaces != 0 {
+		replaced, err := getVerbForm(db, c.Replaces, false)
+		if err == sql.ErrNoRows || err == nil && replaced.infId != infId {
+			return nil, ErrUnrelatedForm
+		} else if err != nil {
+			return nil, err
+		}
+	}
+
+	c.Form.Id = 0
+	form, err := json.Marshal(c.Form)
+	if err != nil {
+		return nil, err
+	}
+	replaces := sql.NullInt64{Int64: int64(c.Replaces), Valid: c.Replaces != 0}
+	var id int
+	err = db.QueryRow(`
+		INSERT INTO corrections (submitter_id, lang_id, replaces_id, form,
+		                         comment)
+		VALUES ($1, $2, $3, $4::jsonb, $5)
+		RETURNING id`,
+		submitterId, c.Form.LanguageId, replaces, string(form), c.Comment,
+	).Scan(&id)
+	if err != nil {
+		return nil, err
+	}
+	return db.GetCorrection(id)
+}
+
+// GetCorrection returns a correction.
+func (db *PsqlDB) GetCorrection(id int) (*Correction, error) {
+	return scanCorrection(db.QueryRow(correctionColumns+`
+		WHERE id = $1`,
+		id,
+	))
+}
+
+// GetCorrections returns a page of the corrections that have a status. If
+// opts.LanguageId is not 0, only corrections in that language are listed.
+func (db *PsqlDB) GetCorrections(status string,
+	opts ListOptions) ([]*Correction, error) {
+	page, args := opts.pageClause(CorrectionSortFields, 2)
+	rows, err := db.Query(correctionColumns+`
+		WHERE status = $1
+		AND   ($2 = 0 OR lang_id = $2)
+		`+page,
+		append([]interface{}{status, opts.LanguageId}, args...)...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var corrections []*Correction
+	for rows.Next() {
+		c, err := scanCorrection(rows)
+		if err != nil {
+			return nil, err
+		}
+		corrections = append(corrections, c)
+	}
+	return corrections, rows.Err()
+}
+
+// ReviewCorrection accepts or rejects a pending correction on behalf of an
+// admin. Accepting it applies its form to verb_forms as an edit by the
+// admin. sql.ErrNoRows is returned if the correction doesn't exist and
+// ErrAlreadyReviewed if it isn't pending.
+func (db *PsqlDB) ReviewCorrection(reviewerId string, id int, accept bool,
+	note string) (*Correction, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	c, err := scanCorrection(tx.QueryRow(correctionColumns+`
+		WHERE id = $1
+		FOR UPDATE`,
+		id,
+	))
+	if err != nil {
+		return nil, err
+	}
+	if c.Status != CorrectionPending {
+		return nil, ErrAlreadyReviewed
+	}
+
+	c.Status = CorrectionRejected
+	if accept {
+		c.Status = CorrectionAccepted
+		var applied *VerbForm
+		if c.Replaces == 0 {
+			applied, err = createVerbForm(tx, reviewerId, &c.Form)
+		} else {
+			applied, err = updateVerbForm(tx, reviewerId, c.Replaces, &c.Form)
+			if err == sql.ErrNoRows {
+				err = ErrStaleCorrection
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		c.Form = *applied
+	}
+
+	form, err := json.Marshal(c.Form)
+	if err != nil {
+		return nil, err
+	}
+	c.ReviewerId, c.ReviewNote = reviewerId, note
+	err = tx.QueryRow(`
+		UPDATE corrections
+		SET status = $2, form = $3::jsonb, reviewer_id = $4, review_note = $5,
+		    reviewed_at = NOW()
+		WHERE id = $1
+		RETURNING reviewed_at`,
+		id, c.Status, string(form), reviewerId, note,
+	).Scan(&c.ReviewedAt)
+	if err != nil {
+		return nil, err
+	}
+	return c, tx.Commit()
+}
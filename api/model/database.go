@@ -5,6 +5,7 @@ import (
 	"fmt"
 	_ "github.com/lib/pq"
 	"log"
+	"mutably/api/model/scheduler"
 )
 
 // A Database facilitates interaction with a collection of data and ensures
@@ -14,16 +15,47 @@ import (
 // If you find yourself using sql.DB directly instead of using a Database
 // implementation, then you're probably doing something wrong.
 type Database interface {
+	TokenStore
+
 	GetLanguage(int) (*Language, error)
-	GetLanguages() ([]*Language, error)
+	GetLanguages(ListOptions) ([]*Language, error)
 	GetWord(int) (*Word, error)
-	GetWords() ([]*Word, error)
+	GetWords(ListOptions) ([]*Word, error)
+	FindWord(languageId int, text string) (*Word, error)
+	CreateWord(editorId string, languageId int, text string) (*Word, error)
+	UpdateWord(editorId string, id int, text string) (*Word, error)
+	DeleteWord(editorId string, id int) error
+	GetWordHistory(wordId int, opts ListOptions) ([]*AuditEntry, error)
+	GetVerbForm(id int) (*VerbForm, error)
+	CreateVerbForm(editorId string, f *VerbForm) (*VerbForm, error)
+	UpdateVerbForm(editorId string, id int, f *VerbForm) (*VerbForm, error)
+	DeleteVerbForm(editorId string, id int) error
+	CreateCorrection(submitterId string, c *Correction) (*Correction, error)
+	GetCorrection(id int) (*Correction, error)
+	GetCorrections(status string, opts ListOptions) ([]*Correction, error)
+	ReviewCorrection(reviewerId string, id int, accept bool, note string) (*Correction, error)
+	SearchWords(query string, opts ListOptions) ([]*Word, error)
+	SuggestWords(languageId int, word string, limit int) ([]*Suggestion, error)
+	AnalyzeWord(languageId int, word string) ([]*Analysis, error)
 	GetUser(string) (*User, error)
-	GetUsers() ([]*User, error)
+	GetUsers(ListOptions) ([]*User, error)
 	CreateUser(string, string) (string, error)
-	IsAdmin(string) bool
+	UpdateUser(id string, changes UserChanges) (*User, error)
+	ChangePassword(id, oldPassword, newPassword string) error
+	DeleteUser(id string) error
+	GetRoles() ([]Role, error)
+	SetUserRole(userId string, role Role) (*User, error)
 	GetUserId(username, password string) string
-	GetConjugationTable(word string) (*ConjugationTable, error)
+	GetConjugationTable(languageId int, word string, compounds []CompoundTense) (*ConjugationTable, error)
+	MeasureRules(languageId int) (*RuleAccuracy, error)
+	CreateDrill(userId string) (*Drill, error)
+	GetDrill(userId string, id int) (*Drill, error)
+	GetDrills(userId string, opts ListOptions) ([]*Drill, error)
+	AnswerDrill(userId string, id int, answer string) (*DrillResult, error)
+	GetDueCards(userId string, limit, newPerDay int) ([]*Card, error)
+	ReviewCard(userId string, id int, grade scheduler.Grade) (*Card, error)
+	RecordResults(userId string, results []*PracticeResult) error
+	GetStats(userId string, languageId, days int) (*Stats, error)
 }
 
 // PsqlDB implements the Database interface for PostgreSQL.
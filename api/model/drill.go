@@ -0,0 +1,326 @@
+package model
+
+import (
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrNoTargetLanguage is returned when a user who hasn't chosen a
+	// language to learn asks for a drill.
+	ErrNoTargetLanguage = errors.New("user has no target language")
+
+	// ErrDrillAnswered is returned when a drill is answered twice.
+	ErrDrillAnswered = errors.New("drill was already answered")
+)
+
+// FormCell identifies one cell of a conjugation table, such as the 3rd
+// person singular past indicative of 'krijgen'.
+type FormCell struct {
+	LanguageId int    `json:"language"`
+	Infinitive string `json:"infinitive"`
+	Tense      string `json:"tense"`
+	Mood       string `json:"mood"`
+
+	// The grammatical person (1, 2, or 3) of a singular form
+	Person int `json:"person,omitempty"`
+
+	// 'singular' or 'plural'
+	Number string `json:"number"`
+
+	// The form in words (e.g., 'krijgen, 3rd person singular past')
+	Prompt string `json:"prompt"`
+
+	// The row values that identify the forms of the cell
+	infId, tenseId, moodId int
+	person                 Person
+	number                 Number
+}
+
+// cellColumns selects, from a table aliased as t that has the columns of a
+// cell, the values that FormCell.scanDest expects. It must be used with
+// cellJoins.
+const (
+	cellColumns = `
+		t.lang_id, inf.word, tenses.tense, moods.mood, t.inf_id, t.tense_id,
+		t.mood_id, COALESCE(t.person, 0), t.num`
+	cellJoins = `
+		JOIN words inf on inf.id    = t.inf_id
+		JOIN tenses    on tenses.id = t.tense_id
+		JOIN moods     on moods.id  = t.mood_id`
+)
+
+// scanDest returns where to scan the values of cellColumns.
+func (c *FormCell) scanDest() []interface{} {
+	return []interface{}{&c.LanguageId, &c.Infinitive, &c.Tense, &c.Mood,
+		&c.infId, &c.tenseId, &c.moodId, &c.person, &c.number}
+}
+
+// finish fills the fields of c that are derived from the scanned values.
+func (c *FormCell) finish() {
+	for i, p := range []Person{First, Second, Third} {
+		if c.person == p {
+			c.Person = i + 1
+		}
+	}
+	c.Number = "plural"
+	if c.number == Singular {
+		c.Number = "singular"
+	}
+	c.Prompt = c.describe()
+}
+
+// describe phrases the form of c.
+func (c *FormCell) describe() string {
+	form := c.Number
+	if c.Person != 0 {
+		form = ordinal(c.Person) + " person " + form
+	}
+
+	prompt := c.Infinitive + ", " + form + " " + c.Tense
+	if c.Mood != "indicative" {
+		prompt += " " + c.Mood
+	}
+	return prompt
+}
+
+// ordinal converts 1, 2, and 3 to '1st', '2nd', and '3rd'.
+func ordinal(n int) string {
+	suffixes := map[int]string{1: "st", 2: "nd", 3: "rd"}
+	return strconv.Itoa(n) + suffixes[n]
+}
+
+// getCellForms returns the forms that fill a cell. A cell may have several
+// (e.g., alternative spellings).
+func (db *PsqlDB) getCellForms(c *FormCell) ([]string, error) {
+	rows, err := db.Query(`
+		SELECT words.word
+		FROM verb_forms
+		JOIN words on words.id = verb_forms.word_id
+		WHERE verb_forms.lang_id = $1
+		AND   inf_id   = $2
+		AND   tense_id = $3
+		AND   mood_id  = $4
+		AND   num      = $5
+		AND   ($6 = 0 OR person & $6 != 0)
+		ORDER BY words.word`,
+		c.LanguageId, c.infId, c.tenseId, c.moodId, c.number, c.person,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	forms := make([]string, 0)
+	for rows.Next() {
+		var form string
+		if err = rows.Scan(&form); err != nil {
+			return nil, err
+		}
+		forms = append(forms, form)
+	}
+	return forms, rows.Err()
+}
+
+// Drill asks a learner for the form of a cell.
+type Drill struct {
+	Id int `json:"id"`
+	FormCell
+
+	CreatedAt time.Time `json:"createdAt"`
+
+	// These are nil until the drill is answered.
+	Answer     *string    `json:"answer,omitempty"`
+	Correct    *bool      `json:"correct,omitempty"`
+	AnsweredAt *time.Time `json:"answeredAt,omitempty"`
+}
+
+// DrillResult is an answered drill along with the answers that were
+// accepted.
+type DrillResult struct {
+	*Drill
+
+	// The forms that would have been correct
+	Expected []string `json:"expected"`
+
+	// What a wrong answer is a form of, if it is any verb form at all
+	Readings []*Analysis `json:"readings,omitempty"`
+}
+
+// DrillSortFields are the fields that drills can be sorted by.
+var DrillSortFields = SortFields{"id": "id", "created": "created_at"}
+
+// randomStart is a WITH clause that picks a random id in the range of the
+// ids of the verb forms of language $2. Reading forms in id order from there
+// and wrapping around gives random ones without sorting the whole language.
+// Forms that follow gaps in the ids are picked a little more often.
+const randomStart = `
+	WITH start AS (
+	  SELECT (min(id) + floor(random() * (max(id) - min(id) + 1)))::int AS id
+	  FROM verb_forms
+	  WHERE lang_id = $2)`
+
+// CreateDrill picks a form of a verb in the target language of a user and
+// returns a new drill that asks for it. A user who has no target language
+// gets ErrNoTargetLanguage, and sql.ErrNoRows is returned if the language
+// has no verbs.
+func (db *PsqlDB) CreateDrill(userId string) (*Drill, error) {
+	var languageId sql.NullInt64
+	err := db.QueryRow(`SELECT target_language_id FROM users WHERE id = $1`,
+		userId).Scan(&languageId)
+	if err != nil {
+		return nil, err
+	}
+	if !languageId.Valid {
+		return nil, ErrNoTargetLanguage
+	}
+
+	// A singular form may be shared by several persons, so one of them is
+	// chosen for the prompt.
+	var id int
+	err = db.QueryRow(randomStart+`
+		INSERT INTO drills (user_id, lang_id, inf_id, tense_id, mood_id,
+		                    person, num)
+		SELECT $1, lang_id, inf_id, tense_id, mood_id,
+		  CASE WHEN num = 1 THEN (
+		    SELECT p FROM unnest(ARRAY[2, 4, 8]) AS p
+		    WHERE person & p != 0
+		    ORDER BY random() LIMIT 1
+		  ) END,
+		  num
+		FROM (
+		  (SELECT lang_id, inf_id, tense_id, mood_id, person, num
+		   FROM verb_forms
+		   WHERE lang_id = $2
+		   AND   (num != 1 OR person IS NOT NULL)
+		   AND   id >= (SELECT id FROM start)
+		   ORDER BY id LIMIT 1)
+		  UNION ALL
+		  (SELECT lang_id, inf_id, tense_id, mood_id, person, num
+		   FROM verb_forms
+		   WHERE lang_id = $2
+		   AND   (num != 1 OR person IS NOT NULL)
+		   AND   id < (SELECT id FROM start)
+		   ORDER BY id LIMIT 1)
+		  LIMIT 1
+		) AS form
+		RETURNING id`,
+		userId, languageId.Int64,
+	).Scan(&id)
+	if err != nil {
+		return nil, err
+	}
+	return db.GetDrill(userId, id)
+}
+
+// drillColumns selects the fields of a Drill. The output names id and
+// created_at can be used in ORDER BY clauses.
+const drillColumns = `
+	SELECT t.id AS id, t.created_at AS created_at, t.answer, t.correct,
+	       t.answered_at,` + cellColumns + `
+	FROM drills t` + cellJoins
+
+// scanDrill reads a row selected with drillColumns.
+func scanDrill(row interface {
+	Scan(...interface{}) error
+}) (*Drill, error) {
+	d := &Drill{}
+	dest := append([]interface{}{&d.Id, &d.CreatedAt, &d.Answer, &d.Correct,
+		&d.AnsweredAt}, d.scanDest()...)
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+	d.finish()
+	return d, nil
+}
+
+// GetDrill returns a drill of a user.
+func (db *PsqlDB) GetDrill(userId string, id int) (*Drill, error) {
+	return scanDrill(db.QueryRow(drillColumns+`
+		WHERE t.id = $1 AND t.user_id = $2`,
+		id, userId,
+	))
+}
+
+// GetDrills returns a page of the drills of a user. If opts.LanguageId is
+// not 0, only drills in that language are listed.
+func (db *PsqlDB) GetDrills(userId string, opts ListOptions) ([]*Drill, error) {
+	page, args := opts.pageClause(DrillSortFields, 2)
+	rows, err := db.Query(drillColumns+`
+		WHERE t.user_id = $1
+		AND   ($2 = 0 OR t.lang_id = $2)
+		`+page,
+		append([]interface{}{userId, opts.LanguageId}, args...)...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var drills []*Drill
+	for rows.Next() {
+		d, err := scanDrill(rows)
+		if err != nil {
+			return nil, err
+		}
+		drills = append(drills, d)
+	}
+	return drills, rows.Err()
+}
+
+// AnswerDrill checks an answer to a drill of a user and records the result.
+// Case and surrounding space are ignored. A drill can only be answered once;
+// later answers get ErrDrillAnswered.
+func (db *PsqlDB) AnswerDrill(userId string, id int,
+	answer string) (*DrillResult, error) {
+	d, err := db.GetDrill(userId, id)
+	if err != nil {
+		return nil, err
+	}
+	if d.Answer != nil {
+		return nil, ErrDrillAnswered
+	}
+
+	result := &DrillResult{Drill: d}
+	result.Expected, err = db.getCellForms(&d.FormCell)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	answer = strings.TrimSpace(answer)
+	correct := contains(result.Expected, answer)
+	err = tx.QueryRow(`
+		UPDATE drills
+		SET answer = $3, correct = $4, answered_at = NOW()
+		WHERE id = $1 AND user_id = $2 AND answered_at IS NULL
+		RETURNING answered_at`,
+		id, userId, answer, correct,
+	).Scan(&d.AnsweredAt)
+	if err == sql.ErrNoRows {
+		// Another request answered it first.
+		return nil, ErrDrillAnswered
+	} else if err != nil {
+		return nil, err
+	}
+	if err = recordResult(tx, userId, &d.FormCell, correct); err != nil {
+		return nil, err
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	d.Answer, d.Correct = &answer, &correct
+
+	if !correct {
+		result.Readings, err = db.AnalyzeWord(d.LanguageId, answer)
+	}
+	return result, err
+}
@@ -0,0 +1,614 @@
+package model
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrWordExists is returned when a word would have the same spelling
+	// as another in its language.
+	ErrWordExists = errors.New("word already exists")
+
+	// ErrWordInUse is returned when a word that other data refers to, such
+	// as the infinitive of verb forms, is deleted.
+	ErrWordInUse = errors.New("word is in use")
+
+	// ErrFormExists is returned when a verb form would duplicate another.
+	ErrFormExists = errors.New("verb form already exists")
+
+	// ErrImportRunning is returned when words would change while anvil
+	// imports into the database.
+	ErrImportRunning = errors.New(
+		"words can't be changed while an import is running")
+
+	// ErrInvalidForm is returned for verb forms whose persons don't match
+	// their number.
+	ErrInvalidForm = errors.New(
+		"singular forms need persons from 1 to 3 and plural forms none")
+)
+
+// PostgreSQL error codes that edits translate
+const (
+	foreignKeyViolation = "23503"
+	uniqueViolation     = "23505"
+)
+
+// isViolation reports whether err is a PostgreSQL error with code.
+func isViolation(err error, code string) bool {
+	e, ok := err.(*pq.Error)
+	return ok && string(e.Code) == code
+}
+
+// wordsLock is the advisory lock that anvil holds while it batches writes.
+// It keeps the id of every word in memory, so words must not be added,
+// renamed, or deleted until it is done. The key must match anvil's.
+const wordsLock = 0x776f726473
+
+// lockWords keeps imports from starting until tx ends. ErrImportRunning is
+// returned if one is already running.
+func lockWords(tx *sql.Tx) error {
+	var locked bool
+	err := tx.QueryRow(`SELECT pg_try_advisory_xact_lock_shared($1)`,
+		wordsLock).Scan(&locked)
+	if err == nil && !locked {
+		err = ErrImportRunning
+	}
+	return err
+}
+
+// queryRower is implemented by both *PsqlDB and *sql.Tx.
+type queryRower interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// VerbForm is one form in the conjugation of a verb.
+type VerbForm struct {
+	Id         int    `json:"id"`
+	LanguageId int    `json:"language"`
+	Word       string `json:"word"`
+	Infinitive string `json:"infinitive"`
+	Tense      string `json:"tense"`
+	Mood       string `json:"mood"`
+
+	// The grammatical persons (1, 2, or 3) that share a singular form
+	Persons []int `json:"persons,omitempty"`
+
+	// 'singular' or 'plural'
+	Number string `json:"number"`
+
+	infId int
+}
+
+// AuditEntry records one change to a word or verb form.
+type AuditEntry struct {
+	Id int `json:"id"`
+
+	// The admin who made the change; empty if they were deleted
+	EditorId string `json:"editor,omitempty"`
+
+	// The word, or infinitive of the verb form, that changed
+	WordId int `json:"word"`
+
+	// 'words' or 'verb_forms', and the id of the row that changed
+	Table string `json:"table"`
+	RowId int    `json:"rowId"`
+
+	// 'create', 'update', or 'delete'
+	Action string `json:"action"`
+
+	// The Word or VerbForm before and after the change. Before is absent
+	// for creations and After for deletions.
+	Before json.RawMessage `json:"before,omitempty"`
+	After  json.RawMessage `json:"after,omitempty"`
+
+	ChangedAt time.Time `json:"changedAt"`
+}
+
+// AuditSortFields are the fields that the history of a word can be sorted
+// by.
+var AuditSortFields = SortFields{"id": "id", "changed": "changed_at"}
+
+// audit records a change that an editor made as part of a transaction.
+// before and after are nil when there was no such value.
+func audit(tx *sql.Tx, editorId string, wordId int, table string, rowId int,
+	action string, before, after interface{}) error {
+	values := make([]sql.NullString, 2)
+	for i, value := range []interface{}{before, after} {
+		if value == nil {
+			continue
+		}
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		values[i] = sql.NullString{String: string(encoded), Valid: true}
+	}
+
+	_, err := tx.Exec(`
+		INSERT INTO audit_log (editor_id, word_id, table_name, row_id, action,
+		                       before, after)
+		VALUES ($1, $2, $3, $4, $5, $6::jsonb, $7::jsonb)`,
+		editorId, wordId, table, rowId, action, values[0], values[1],
+	)
+	return err
+}
+
+// CreateWord adds a word to a language on behalf of an editor.
+func (db *PsqlDB) CreateWord(editorId string, languageId int,
+	text string) (*Word, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	word, err := createWord(tx, editorId, languageId, text)
+	if err != nil {
+		return nil, err
+	}
+	return word, tx.Commit()
+}
+
+// createWord adds a word as part of a transaction.
+func createWord(tx *sql.Tx, editorId string, languageId int,
+	text string) (*Word, error) {
+	if err := lockWords(tx); err != nil {
+		return nil, err
+	}
+
+	word := &Word{Text: text, LanguageId: languageId}
+	err := tx.QueryRow(`
+		INSERT INTO words (lang_id, word) VALUES ($1, $2)
+		RETURNING id`,
+		languageId, text,
+	).Scan(&word.Id)
+	switch {
+	case isViolation(err, uniqueViolation):
+		return nil, ErrWordExists
+	case isViolation(err, foreignKeyViolation):
+		return nil, ErrUnknownLanguage
+	case err != nil:
+		return nil, err
+	}
+
+	err = audit(tx, editorId, word.Id, "words", word.Id, "create", nil, word)
+	if err != nil {
+		return nil, err
+	}
+	return word, nil
+}
+
+// lockWord returns a word and keeps others from changing it until tx ends.
+func lockWord(tx *sql.Tx, id int) (*Word, error) {
+	word := &Word{Id: id}
+	err := tx.QueryRow(`
+		SELECT word, lang_id FROM words
+		WHERE id = $1
+		FOR UPDATE`,
+		id,
+	).Scan(&word.Text, &word.LanguageId)
+	if err != nil {
+		return nil, err
+	}
+	return word, nil
+}
+
+// UpdateWord changes the spelling of a word on behalf of an editor.
+// sql.ErrNoRows is returned if the word doesn't exist.
+func (db *PsqlDB) UpdateWord(editorId string, id int,
+	text string) (*Word, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err = lockWords(tx); err != nil {
+		return nil, err
+	}
+	before, err := lockWord(tx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(`UPDATE words SET word = $2 WHERE id = $1`, id, text)
+	if isViolation(err, uniqueViolation) {
+		return nil, ErrWordExists
+	} else if err != nil {
+		return nil, err
+	}
+
+	after := &Word{Id: id, Text: text, LanguageId: before.LanguageId}
+	err = audit(tx, editorId, id, "words", id, "update", before, after)
+	if err != nil {
+		return nil, err
+	}
+	return after, tx.Commit()
+}
+
+// DeleteWord removes a word on behalf of an editor. Words that other data
+// refers to can't be deleted; they get ErrWordInUse. sql.ErrNoRows is
+// returned if the word doesn't exist.
+func (db *PsqlDB) DeleteWord(editorId string, id int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err = lockWords(tx); err != nil {
+		return err
+	}
+	before, err := lockWord(tx, id)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`DELETE FROM words WHERE id = $1`, id)
+	if isViolation(err, foreignKeyViolation) {
+		return ErrWordInUse
+	} else if err != nil {
+		return err
+	}
+
+	err = audit(tx, editorId, id, "words", id, "delete", before, nil)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// verbFormColumns selects the fields of a VerbForm.
+const verbFormColumns = `
+	SELECT vf.id, vf.lang_id, form.word, inf.word, tenses.tense, moods.mood,
+	       COALESCE(vf.person, 0), vf.num, vf.inf_id
+	FROM verb_forms vf
+	JOIN words form on form.id   = vf.word_id
+	JOIN words inf  on inf.id    = vf.inf_id
+	JOIN tenses     on tenses.id = vf.tense_id
+	JOIN moods      on moods.id  = vf.mood_id`
+
+// getVerbForm reads a verb form. If lock is true, others can't change it
+// until the transaction that db belongs to ends.
+func getVerbForm(db queryRower, id int, lock bool) (*VerbForm, error) {
+	query := verbFormColumns + `
+		WHERE vf.id = $1`
+	if lock {
+		query += ` FOR UPDATE OF vf`
+	}
+
+	f := &VerbForm{}
+	var person Person
+	var number Number
+	err := db.QueryRow(query, id).Scan(&f.Id, &f.LanguageId, &f.Word,
+		&f.Infinitive, &f.Tense, &f.Mood, &person, &number, &f.infId)
+	if err != nil {
+		return nil, err
+	}
+
+	f.Number = "plural"
+	if number == Singular {
+		f.Number = "singular"
+	}
+	for i, p := range []Person{First, Second, Third} {
+		if person&p != 0 {
+			f.Persons = append(f.Persons, i+1)
+		}
+	}
+	return f, nil
+}
+
+// GetVerbForm returns a verb form by its id.
+func (db *PsqlDB) GetVerbForm(id int) (*VerbForm, error) {
+	return getVerbForm(db, id, false)
+}
+
+// formRow holds the column values of a verb form.
+type formRow struct {
+	wordId, infId, tenseId, moodId int
+	person                         sql.NullInt64
+	number                         Number
+}
+
+// personAndNumber returns the person and number columns of f, or
+// ErrInvalidForm if its persons don't match its number.
+func (f *VerbForm) personAndNumber() (sql.NullInt64, Number, error) {
+	switch {
+	case f.Number == "plural" && len(f.Persons) == 0:
+		return sql.NullInt64{}, Plural, nil
+	case f.Number == "singular" && len(f.Persons) > 0:
+		var person Person
+		for _, p := range f.Persons {
+			if p < 1 || p > 3 {
+				return sql.NullInt64{}, 0, ErrInvalidForm
+			}
+			person |= []Person{First, Second, Third}[p-1]
+		}
+		return sql.NullInt64{Int64: int64(person), Valid: true}, Singular, nil
+	default:
+		return sql.NullInt64{}, 0, ErrInvalidForm
+	}
+}
+
+// findCell returns the ids of the infinitive, tense, and mood (indicative
+// if empty) of f, or ErrUnknownCell if one doesn't exist.
+func findCell(db queryRower, f *VerbForm) (infId, tenseId, moodId int,
+	err error) {
+	if f.Mood == "" {
+		f.Mood = "indicative"
+	}
+	err = db.QueryRow(`
+		SELECT inf.id, tenses.id, moods.id
+		FROM words inf, tenses, moods
+		WHERE inf.lang_id = $1
+		AND   inf.word    = $2
+		AND   tenses.tense = $3
+		AND   moods.mood   = $4`,
+		f.LanguageId, f.Infinitive, f.Tense, f.Mood,
+	).Scan(&infId, &tenseId, &moodId)
+	if err == sql.ErrNoRows {
+		err = ErrUnknownCell
+	}
+	return
+}
+
+// resolveForm finds the column values of f. The infinitive, tense, and mood
+// must exist, but the form's word is created if it doesn't.
+func resolveForm(tx *sql.Tx, editorId string, f *VerbForm) (*formRow, error) {
+	row := &formRow{}
+	var err error
+	row.person, row.number, err = f.personAndNumber()
+	if err != nil {
+		return nil, err
+	}
+	row.infId, row.tenseId, row.moodId, err = findCell(tx, f)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.QueryRow(`SELECT id FROM words WHERE lang_id = $1 AND word = $2`,
+		f.LanguageId, f.Word).Scan(&row.wordId)
+	if err == sql.ErrNoRows {
+		var word *Word
+		word, err = createWord(tx, editorId, f.LanguageId, f.Word)
+		if word != nil {
+			row.wordId = word.Id
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Conjugation tables are only built for words listed as infinitives.
+	_, err = tx.Exec(`
+		INSERT INTO infinitives (lang_id, word_id) VALUES ($1, $2)
+		ON CONFLICT DO NOTHING`,
+		f.LanguageId, row.infId,
+	)
+	return row, err
+}
+
+// CreateVerbForm adds a form to the conjugation of a verb on behalf of an
+// editor. The infinitive, tense, and mood (indicative if empty) must exist;
+// ErrUnknownCell is returned otherwise.
+func (db *PsqlDB) CreateVerbForm(editorId string,
+	f *VerbForm) (*VerbForm, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	created, err := createVerbForm(tx, editorId, f)
+	if err != nil {
+		return nil, err
+	}
+	return created, tx.Commit()
+}
+
+// createVerbForm adds a verb form as part of a transaction.
+func createVerbForm(tx *sql.Tx, editorId string,
+	f *VerbForm) (*VerbForm, error) {
+	row, err := resolveForm(tx, editorId, f)
+	if err != nil {
+		return nil, err
+	}
+
+	var id int
+	err = tx.QueryRow(`
+		INSERT INTO verb_forms (lang_id, word_id, inf_id, tense_id, mood_id,
+		                        person, num)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id`,
+		f.LanguageId, row.wordId, row.infId, row.tenseId, row.moodId,
+		row.person, row.number,
+	).Scan(&id)
+	if isViolation(err, uniqueViolation) {
+		return nil, ErrFormExists
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err = removeTombstone(tx, id); err != nil {
+		return nil, err
+	}
+	after, err := getVerbForm(tx, id, false)
+	if err != nil {
+		return nil, err
+	}
+	err = audit(tx, editorId, after.infId, "verb_forms", id, "create", nil,
+		after)
+	if err != nil {
+		return nil, err
+	}
+	return after, nil
+}
+
+// UpdateVerbForm replaces a verb form on behalf of an editor. It accepts
+// the same values as CreateVerbForm. sql.ErrNoRows is returned if the form
+// doesn't exist.
+//
+// An updated form counts as added by hand: updates of the page it was
+// imported from keep it, and imports skip the form it replaced.
+func (db *PsqlDB) UpdateVerbForm(editorId string, id int,
+	f *VerbForm) (*VerbForm, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	updated, err := updateVerbForm(tx, editorId, id, f)
+	if err != nil {
+		return nil, err
+	}
+	return updated, tx.Commit()
+}
+
+// updateVerbForm replaces a verb form as part of a transaction.
+func updateVerbForm(tx *sql.Tx, editorId string, id int,
+	f *VerbForm) (*VerbForm, error) {
+	before, err := getVerbForm(tx, id, true)
+	if err != nil {
+		return nil, err
+	}
+	row, err := resolveForm(tx, editorId, f)
+	if err != nil {
+		return nil, err
+	}
+
+	// The form no longer comes from its page, so an update of the page must
+	// neither delete it nor add back what it replaced.
+	if err = addTombstone(tx, id); err != nil {
+		return nil, err
+	}
+	_, err = tx.Exec(`
+		UPDATE verb_forms
+		SET lang_id = $2, word_id = $3, inf_id = $4, tense_id = $5,
+		    mood_id = $6, person = $7, num = $8, page_id = NULL
+		WHERE id = $1`,
+		id, f.LanguageId, row.wordId, row.infId, row.tenseId, row.moodId,
+		row.person, row.number,
+	)
+	if isViolation(err, uniqueViolation) {
+		return nil, ErrFormExists
+	} else if err != nil {
+		return nil, err
+	}
+	if err = removeTombstone(tx, id); err != nil {
+		return nil, err
+	}
+
+	after, err := getVerbForm(tx, id, false)
+	if err != nil {
+		return nil, err
+	}
+	err = audit(tx, editorId, after.infId, "verb_forms", id, "update", before,
+		after)
+	if err != nil {
+		return nil, err
+	}
+	return after, nil
+}
+
+// DeleteVerbForm removes a verb form on behalf of an editor. sql.ErrNoRows
+// is returned if the form doesn't exist. Imports skip the form from then on,
+// unless an editor creates it again.
+func (db *PsqlDB) DeleteVerbForm(editorId string, id int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := getVerbForm(tx, id, true)
+	if err != nil {
+		return err
+	}
+	if err = addTombstone(tx, id); err != nil {
+		return err
+	}
+	if _, err = tx.Exec(`DELETE FROM verb_forms WHERE id = $1`, id); err != nil {
+		return err
+	}
+
+	err = audit(tx, editorId, before.infId, "verb_forms", id, "delete", before,
+		nil)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// addTombstone records that an editor removed the verb form with id, so that
+// importing its page again doesn't add it back.
+func addTombstone(tx *sql.Tx, id int) error {
+	_, err := tx.Exec(`
+		INSERT INTO deleted_verb_forms
+		  (lang_id, word_id, inf_id, tense_id, mood_id, person, num)
+		SELECT lang_id, word_id, inf_id, tense_id, mood_id,
+		       COALESCE(person, 0), num
+		FROM verb_forms
+		WHERE id = $1
+		ON CONFLICT DO NOTHING`,
+		id,
+	)
+	return err
+}
+
+// removeTombstone forgets that the verb form with id was removed, since an
+// editor has added it again.
+func removeTombstone(tx *sql.Tx, id int) error {
+	_, err := tx.Exec(`
+		DELETE FROM deleted_verb_forms deleted
+		USING verb_forms vf
+		WHERE vf.id = $1
+		AND   deleted.lang_id  = vf.lang_id
+		AND   deleted.word_id  = vf.word_id
+		AND   deleted.inf_id   = vf.inf_id
+		AND   deleted.tense_id = vf.tense_id
+		AND   deleted.mood_id  = vf.mood_id
+		AND   deleted.person   = COALESCE(vf.person, 0)
+		AND   deleted.num      = vf.num`,
+		id,
+	)
+	return err
+}
+
+// GetWordHistory returns a page of the changes made to a word and, if it is
+// an infinitive, to its verb forms.
+func (db *PsqlDB) GetWordHistory(wordId int,
+	opts ListOptions) ([]*AuditEntry, error) {
+	page, args := opts.pageClause(AuditSortFields, 1)
+	rows, err := db.Query(`
+		SELECT id, COALESCE(editor_id::text, ''), word_id, table_name, row_id,
+		       action, before, after, changed_at
+		FROM audit_log
+		WHERE word_id = $1
+		`+page,
+		append([]interface{}{wordId}, args...)...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []*AuditEntry
+	for rows.Next() {
+		e := &AuditEntry{}
+		var before, after []byte
+		err = rows.Scan(&e.Id, &e.EditorId, &e.WordId, &e.Table, &e.RowId,
+			&e.Action, &before, &after, &e.ChangedAt)
+		if err != nil {
+			return nil, err
+		}
+		e.Before, e.After = before, after
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
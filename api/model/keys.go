@@ -0,0 +1,358 @@
+package model
+
+import (
+	"bytes"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"log"
+	"math/big"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"golang.org/x/crypto/ed25519"
+)
+
+// ErrUnknownKey is returned for tokens that no known key signed.
+var ErrUnknownKey = errors.New("token was signed by an unknown key")
+
+// keyReloadInterval is how often the key directory is read again. A new key
+// only signs tokens once it is that old so that every API instance can
+// verify them.
+const keyReloadInterval = time.Minute
+
+// SigningMethodEdDSA signs tokens with Ed25519 keys, as RFC 8037 defines.
+var SigningMethodEdDSA jwt.SigningMethod = signingMethodEdDSA{}
+
+func init() {
+	jwt.RegisterSigningMethod(SigningMethodEdDSA.Alg(),
+		func() jwt.SigningMethod { return SigningMethodEdDSA })
+}
+
+type signingMethodEdDSA struct{}
+
+func (signingMethodEdDSA) Alg() string {
+	return "EdDSA"
+}
+
+func (signingMethodEdDSA) Verify(signingString, signature string,
+	key interface{}) error {
+	publicKey, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return jwt.ErrInvalidKeyType
+	}
+	sig, err := jwt.DecodeSegment(signature)
+	if err != nil {
+		return err
+	}
+	if !ed25519.Verify(publicKey, []byte(signingString), sig) {
+		return jwt.ErrSignatureInvalid
+	}
+	return nil
+}
+
+func (signingMethodEdDSA) Sign(signingString string,
+	key interface{}) (string, error) {
+	privateKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return "", jwt.ErrInvalidKeyType
+	}
+	return jwt.EncodeSegment(ed25519.Sign(privateKey, []byte(signingString))),
+		nil
+}
+
+// signingKey is an asymmetric key pair that signs access tokens.
+type signingKey struct {
+	// The key id that tokens name in their kid header
+	id string
+
+	method     jwt.SigningMethod
+	privateKey interface{}
+	publicKey  interface{}
+
+	// When the key was added to the key directory
+	added time.Time
+}
+
+// JWK describes a public key, as RFC 7517 defines.
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyId     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+
+	// The modulus and exponent of RSA keys
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+
+	// The curve and public key of Ed25519 keys
+	Curve string `json:"crv,omitempty"`
+	X     string `json:"x,omitempty"`
+}
+
+// JWKSet is the set of public keys that verify access tokens.
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// jwk returns the public half of key.
+func (key *signingKey) jwk() JWK {
+	encode := base64.RawURLEncoding.EncodeToString
+	jwk := JWK{KeyId: key.id, Use: "sig", Algorithm: key.method.Alg()}
+	switch publicKey := key.publicKey.(type) {
+	case *rsa.PublicKey:
+		jwk.KeyType = "RSA"
+		jwk.N = encode(publicKey.N.Bytes())
+		jwk.E = encode(big.NewInt(int64(publicKey.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.KeyType = "OKP"
+		jwk.Curve = "Ed25519"
+		jwk.X = encode(publicKey)
+	}
+	return jwk
+}
+
+// keySet holds the keys that sign and verify access tokens. The keys are
+// read from a directory of PEM files that is read again every
+// keyReloadInterval. Keys are rotated by adding a new file, which signs
+// tokens once it is keyReloadInterval old, and removing the old file once
+// the tokens it signed have expired.
+//
+// A shared secret can also verify HS256 tokens that have no key id. That
+// lets tokens from before the key directory was used expire gracefully. If
+// there is a key directory, only tokens that expire within one access token
+// lifetime of it being first loaded are accepted, so the secret stops
+// verifying tokens once those have expired.
+type keySet struct {
+	dir    string
+	secret []byte
+	// When the key directory was first loaded
+	firstLoaded time.Time
+
+	mu       sync.RWMutex
+	keys     map[string]*signingKey
+	loadedAt time.Time
+}
+
+// newKeySet returns the keys in dir along with secret. Either can be empty,
+// but not both.
+func newKeySet(dir, secret string) (*keySet, error) {
+	ks := &keySet{dir: dir, secret: []byte(secret)}
+	if dir == "" {
+		if secret == "" {
+			return nil, errors.New("a key directory or secret is required")
+		}
+		return ks, nil
+	}
+
+	var err error
+	ks.keys, err = loadKeys(dir)
+	if err == nil && len(ks.keys) == 0 {
+		err = errors.New("no keys found in " + dir)
+	}
+	if err != nil {
+		return nil, err
+	}
+	ks.loadedAt = time.Now()
+	ks.firstLoaded = ks.loadedAt
+	return ks, nil
+}
+
+// loadKeys reads the keys in dir. Each file named <kid>.pem holds one
+// PKCS #1 or #8 RSA key or PKCS #8 Ed25519 key.
+func loadKeys(dir string) (map[string]*signingKey, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]*signingKey)
+	for _, file := range files {
+		if file.IsDir() || filepath.Ext(file.Name()) != ".pem" {
+			continue
+		}
+		contents, err := ioutil.ReadFile(filepath.Join(dir, file.Name()))
+		if err != nil {
+			return nil, err
+		}
+		key, err := parseKey(contents)
+		if err != nil {
+			return nil, errors.New(file.Name() + ": " + err.Error())
+		}
+		key.id = strings.TrimSuffix(file.Name(), ".pem")
+		key.added = file.ModTime()
+		keys[key.id] = key
+	}
+	return keys, nil
+}
+
+// The object identifier of Ed25519 keys, from RFC 8410
+var oidEd25519 = asn1.ObjectIdentifier{1, 3, 101, 112}
+
+// pkcs8 is the ASN.1 structure of PKCS #8 private keys.
+type pkcs8 struct {
+	Version    int
+	Algorithm  algorithmIdentifier
+	PrivateKey []byte
+}
+
+type algorithmIdentifier struct {
+	Algorithm  asn1.ObjectIdentifier
+	Parameters asn1.RawValue `asn1:"optional"`
+}
+
+// parseKey reads a private key from a PEM block.
+func parseKey(contents []byte) (*signingKey, error) {
+	block, _ := pem.Decode(contents)
+	if block == nil {
+		return nil, errors.New("expected a PEM encoded private key")
+	}
+
+	// crypto/x509 doesn't parse Ed25519 keys, so they are read by hand.
+	var info pkcs8
+	_, err := asn1.Unmarshal(block.Bytes, &info)
+	if err == nil && info.Algorithm.Algorithm.Equal(oidEd25519) {
+		var seed []byte
+		_, err = asn1.Unmarshal(info.PrivateKey, &seed)
+		if err != nil || len(seed) != 32 {
+			return nil, errors.New("invalid Ed25519 private key")
+		}
+		publicKey, privateKey, err := ed25519.GenerateKey(
+			bytes.NewReader(seed))
+		if err != nil {
+			return nil, err
+		}
+		return &signingKey{method: SigningMethodEdDSA,
+			privateKey: privateKey, publicKey: publicKey}, nil
+	}
+
+	parsed, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err != nil {
+		generic, _ := x509.ParsePKCS8PrivateKey(block.Bytes)
+		parsed, _ = generic.(*rsa.PrivateKey)
+	}
+	if parsed == nil {
+		return nil, errors.New("expected an RSA or Ed25519 private key")
+	}
+	return &signingKey{method: jwt.SigningMethodRS256,
+		privateKey: parsed, publicKey: &parsed.PublicKey}, nil
+}
+
+// reload reads the key directory again if it is due. Problems are logged,
+// and the keys that were loaded before are kept.
+func (ks *keySet) reload() {
+	if ks.dir == "" {
+		return
+	}
+	ks.mu.RLock()
+	due := time.Since(ks.loadedAt) >= keyReloadInterval
+	ks.mu.RUnlock()
+	if !due {
+		return
+	}
+
+	keys, err := loadKeys(ks.dir)
+	if err == nil && len(keys) == 0 {
+		err = errors.New("no keys found in " + ks.dir)
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	ks.loadedAt = time.Now()
+	if err != nil {
+		log.Println("Could not reload keys;", err)
+		return
+	}
+	ks.keys = keys
+}
+
+// signer returns the key that should sign new tokens, or nil if tokens
+// should be signed with the secret. It is the newest key that every API
+// instance has had time to load, or the oldest key if none has.
+func (ks *keySet) signer() *signingKey {
+	ks.reload()
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	settled := time.Now().Add(-keyReloadInterval)
+	var newest, oldest *signingKey
+	for _, key := range ks.keys {
+		if !key.added.After(settled) && (newest == nil ||
+			key.added.After(newest.added) ||
+			key.added.Equal(newest.added) && key.id > newest.id) {
+			newest = key
+		}
+		if oldest == nil || key.added.Before(oldest.added) {
+			oldest = key
+		}
+	}
+	if newest == nil {
+		return oldest
+	}
+	return newest
+}
+
+// verifier is a jwt.Keyfunc that finds the key that a token claims to be
+// signed by. The token's algorithm must match the key's so that a public
+// key can't be passed off as an HMAC secret.
+func (ks *keySet) verifier(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		if len(ks.secret) == 0 ||
+			token.Method.Alg() != jwt.SigningMethodHS256.Alg() ||
+			!ks.signedBeforeKeys(token) {
+			return nil, ErrUnknownKey
+		}
+		return ks.secret, nil
+	}
+
+	ks.reload()
+	ks.mu.RLock()
+	key := ks.keys[kid]
+	ks.mu.RUnlock()
+	if key == nil || token.Method.Alg() != key.method.Alg() {
+		return nil, ErrUnknownKey
+	}
+	return key.publicKey, nil
+}
+
+// signedBeforeKeys reports whether a token without a key id could have been
+// signed before the key directory was first loaded, judging by when it
+// expires. The exp claim is used rather than iat because iat doesn't limit
+// how long a token lasts. It is always true if there is no key directory.
+func (ks *keySet) signedBeforeKeys(token *jwt.Token) bool {
+	if ks.dir == "" {
+		return true
+	}
+	claims, _ := token.Claims.(jwt.MapClaims)
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return false
+	}
+	return !time.Unix(int64(exp), 0).After(
+		ks.firstLoaded.Add(accessTokenLifetime))
+}
+
+// publicKeys returns the public halves of the keys, sorted by id.
+func (ks *keySet) publicKeys() *JWKSet {
+	ks.reload()
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	set := &JWKSet{Keys: []JWK{}}
+	for _, key := range ks.keys {
+		set.Keys = append(set.Keys, key.jwk())
+	}
+	sort.Slice(set.Keys, func(i, j int) bool {
+		return set.Keys[i].KeyId < set.Keys[j].KeyId
+	})
+	return set
+}
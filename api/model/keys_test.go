@@ -0,0 +1,175 @@
+package model_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/pem"
+	"io/ioutil"
+	"mutably/api/model"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"golang.org/x/crypto/ed25519"
+)
+
+// tokenStore is a TokenStore in which every user has token version 0.
+type tokenStore struct{}
+
+func (tokenStore) GetTokenVersion(string) (int, error)                { return 0, nil }
+func (tokenStore) GetUserRole(string) (model.Role, error)             { return model.RoleUser, nil }
+func (tokenStore) CreateRefreshToken(string, string, time.Time) error { return nil }
+func (tokenStore) UseRefreshToken(string) (string, error)             { return "", nil }
+func (tokenStore) RevokeRefreshToken(string, string) error            { return nil }
+func (tokenStore) RevokeTokens(string) error                          { return nil }
+
+// writeKey saves a PEM encoded key in dir as kid.pem, last modified at
+// modified.
+func writeKey(t *testing.T, dir, kid string, der []byte, modified time.Time) {
+	t.Helper()
+	path := filepath.Join(dir, kid+".pem")
+	contents := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	if err := ioutil.WriteFile(path, contents, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modified, modified); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// ed25519Key returns a new Ed25519 key in PKCS #8 form.
+func ed25519Key(t *testing.T) []byte {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seed, _ := asn1.Marshal(key[:32])
+	der, err := asn1.Marshal(struct {
+		Version    int
+		Algorithm  struct{ Algorithm asn1.ObjectIdentifier }
+		PrivateKey []byte
+	}{0, struct{ Algorithm asn1.ObjectIdentifier }{
+		asn1.ObjectIdentifier{1, 3, 101, 112}}, seed})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return der
+}
+
+// newAuthLayer creates an AuthLayer from a key directory and secret.
+func newAuthLayer(dir, secret string) *model.AuthLayer {
+	os.Setenv("API_KEY_DIR", dir)
+	os.Setenv("API_PRIVATE_KEY", secret)
+	return model.NewAuthLayer(tokenStore{})
+}
+
+// verify reports whether auth accepts token.
+func verify(auth *model.AuthLayer, token string) bool {
+	r, _ := http.NewRequest("GET", "/", nil)
+	r.Header.Set("Authorization", "Bearer "+token)
+	_, err := auth.GetClaims(r)
+	return err == nil
+}
+
+// The newest key that has been in the directory for a while should sign
+// tokens, and every key should verify them.
+func TestAuthLayer_keyRotation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "keys")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	writeKey(t, dir, "2018-01", x509.MarshalPKCS1PrivateKey(rsaKey),
+		now.Add(-48*time.Hour))
+	writeKey(t, dir, "2018-02", ed25519Key(t), now.Add(-time.Hour))
+	writeKey(t, dir, "2018-03", ed25519Key(t), now)
+	legacy, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"id": "user", "exp": now.Add(time.Hour).Unix()},
+	).SignedString([]byte("secret"))
+	auth := newAuthLayer(dir, "secret")
+
+	tokens, err := auth.IssueTokens("user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, _ := jwt.Parse(tokens.Token, nil)
+	if parsed.Header["kid"] != "2018-02" || parsed.Header["alg"] != "EdDSA" {
+		t.Error("Expected key 2018-02 to sign with EdDSA, got", parsed.Header)
+	}
+	if !verify(auth, tokens.Token) {
+		t.Error("Expected the signed token to verify")
+	}
+
+	old := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{"id": "user"})
+	old.Header["kid"] = "2018-01"
+	signed, _ := old.SignedString(rsaKey)
+	if !verify(auth, signed) {
+		t.Error("Expected a token of an older key to verify")
+	}
+
+	if !verify(auth, legacy) {
+		t.Error("Expected a token signed with the secret to verify")
+	}
+
+	// Tokens signed with the secret after the keys were loaded, or that
+	// never expire, should not verify.
+	for _, claims := range []jwt.MapClaims{
+		{"id": "user", "exp": now.Add(2 * time.Hour).Unix()},
+		{"id": "user"},
+	} {
+		late, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).
+			SignedString([]byte("secret"))
+		if verify(auth, late) {
+			t.Error("Expected a token signed with the secret to be rejected:",
+				claims)
+		}
+	}
+
+	keys := auth.PublicKeys().Keys
+	if len(keys) != 3 || keys[0].KeyType != "RSA" || keys[1].Curve != "Ed25519" {
+		t.Error("Expected an RSA key and two Ed25519 keys, got", keys)
+	}
+}
+
+// Tokens should only verify with a key of their algorithm, so that public
+// keys can't be used as HMAC secrets.
+func TestAuthLayer_algorithmMismatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "keys")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeKey(t, dir, "rsa", x509.MarshalPKCS1PrivateKey(rsaKey), time.Now())
+	auth := newAuthLayer(dir, "")
+
+	public, _ := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"id": "x"})
+	forged.Header["kid"] = "rsa"
+	signed, _ := forged.SignedString(public)
+	if verify(auth, signed) {
+		t.Error("Expected an HS256 token to be rejected by an RSA key")
+	}
+
+	unsigned, _ := jwt.NewWithClaims(jwt.SigningMethodHS256,
+		jwt.MapClaims{"id": "x"}).SignedString([]byte(""))
+	if verify(auth, unsigned) {
+		t.Error("Expected tokens without a key id to be rejected")
+	}
+}
@@ -0,0 +1,56 @@
+package model
+
+import "fmt"
+
+// ListOptions selects one page of a collection.
+type ListOptions struct {
+	// The maximum number of items in the page
+	Limit int
+
+	// The number of items that come before the page
+	Offset int
+
+	// The field to sort by; it must be a key of the collection's SortFields
+	Sort string
+
+	// True if items are sorted from greatest to least
+	Descending bool
+
+	// If not 0, only items of this language are listed
+	LanguageId int
+}
+
+// SortFields maps the fields a collection can be sorted by to their columns.
+type SortFields map[string]string
+
+// The fields that each collection can be sorted by. The first listed field
+// of each is the id, which is also the default.
+var (
+	WordSortFields     = SortFields{"id": "id", "text": "word", "language": "lang_id"}
+	LanguageSortFields = SortFields{"id": "id", "name": "name", "tag": "tag"}
+	UserSortFields     = SortFields{"id": "id", "name": "name", "created": "created_at"}
+)
+
+// pageClause returns the ORDER BY, LIMIT, and OFFSET clauses that select
+// the page described by opts. Its placeholders are numbered after the
+// first argCount and are bound to the returned arguments. Ties are broken
+// by id so that rows don't move between pages.
+func (opts ListOptions) pageClause(fields SortFields,
+	argCount int) (string, []interface{}) {
+	column, ok := fields[opts.Sort]
+	if !ok {
+		column = "id"
+	}
+	direction := "ASC"
+	if opts.Descending {
+		direction = "DESC"
+	}
+
+	order := column + " " + direction
+	if column != "id" {
+		order += ", id " + direction
+	}
+	clause := fmt.Sprintf("ORDER BY %s LIMIT $%d OFFSET $%d", order,
+		argCount+1, argCount+2)
+	return clause, []interface{}{opts.Limit, opts.Offset}
+}
@@ -3,7 +3,9 @@ package model
 import (
 	"database/sql"
 	"errors"
+	"strings"
 	"time"
+	"unicode/utf8"
 )
 
 // Language describes a natural language that exists in the database.
@@ -34,9 +36,10 @@ func (db *PsqlDB) GetLanguage(id int) (*Language, error) {
 	return language, nil
 }
 
-// GetLanguages returns a slice of all languages in the database.
-func (db *PsqlDB) GetLanguages() ([]*Language, error) {
-	rows, err := db.Query(`SELECT * FROM languages`)
+// GetLanguages returns a page of the languages in the database.
+func (db *PsqlDB) GetLanguages(opts ListOptions) ([]*Language, error) {
+	page, args := opts.pageClause(LanguageSortFields, 0)
+	rows, err := db.Query(`SELECT id, name, tag FROM languages `+page, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -68,15 +71,111 @@ type Word struct {
 	LanguageId int `json:"language"`
 }
 
-// GetWords returns a slice of all words in the database.
-func (db *PsqlDB) GetWords() ([]*Word, error) {
+// GetWords returns a page of the words in the database. If
+// opts.LanguageId is not 0, only words of that language are listed.
+func (db *PsqlDB) GetWords(opts ListOptions) ([]*Word, error) {
+	page, args := opts.pageClause(WordSortFields, 1)
+	return db.queryWords(`
+		SELECT id, word, lang_id FROM words
+		WHERE ($1 = 0 OR lang_id = $1)
+		`+page,
+		append([]interface{}{opts.LanguageId}, args...)...,
+	)
+}
+
+// SearchWords returns a page of the words that contain query, ignoring case
+// and accents. Results are ranked, so opts.Sort is not used: exact matches
+// come first, then words that begin with query, and then the rest by
+// similarity. If opts.LanguageId is not 0, only words of that language are
+// searched.
+func (db *PsqlDB) SearchWords(query string, opts ListOptions) ([]*Word, error) {
+	// The expression lower(f_unaccent(word)) matches the words_search index.
+	return db.queryWords(`
+		SELECT id, word, lang_id FROM words
+		WHERE lower(f_unaccent(word)) LIKE '%' || lower(f_unaccent($2)) || '%'
+		AND   ($3 = 0 OR lang_id = $3)
+		ORDER BY
+		  lower(f_unaccent(word)) = lower(f_unaccent($1)) DESC,
+		  lower(f_unaccent(word)) LIKE lower(f_unaccent($2)) || '%' DESC,
+		  similarity(lower(f_unaccent(word)), lower(f_unaccent($1))) DESC,
+		  word, id
+		LIMIT $4 OFFSET $5`,
+		query, escapeLike(query), opts.LanguageId, opts.Limit, opts.Offset,
+	)
+}
+
+// Suggestion is a known word that is spelled like one that was not found.
+type Suggestion struct {
+	Word
+
+	// The number of single character edits between the two words
+	Distance int `json:"distance"`
+}
+
+// The number of words most similar by trigrams that SuggestWords ranks by
+// edit distance
+const suggestionCandidates = 50
+
+// SuggestWords returns the words nearest to word by edit distance, ignoring
+// case. Words more than a few edits away are not considered. If languageId
+// is not 0, only words of that language are suggested. At most limit
+// suggestions are returned, nearest first.
+//
+// Only words that share enough trigrams with word to pass pg_trgm's
+// similarity threshold are compared, since those can be found with the
+// words_search index. Very short words with a typo may share too few.
+func (db *PsqlDB) SuggestWords(languageId int, word string,
+	limit int) ([]*Suggestion, error) {
+	// Longer words leave more room for typos.
+	maxDistance := 1 + utf8.RuneCountInString(word)/4
+	if maxDistance > 3 {
+		maxDistance = 3
+	}
+
+	// The expression lower(f_unaccent(word)) matches the words_search index.
 	rows, err := db.Query(`
-		SELECT DISTINCT words.id, words.word, lang_id
-		FROM verb_forms
-		JOIN words
-		  on verb_forms.word_id = words.id
-		  or verb_forms.inf_id  = words.id
-	`)
+		SELECT id, word, lang_id, distance FROM (
+		  SELECT id, word, lang_id,
+		    levenshtein_less_equal(lower(word), lower($2), $3) AS distance
+		  FROM words
+		  WHERE lower(f_unaccent(word)) % lower(f_unaccent($2))
+		  AND   ($1 = 0 OR lang_id = $1)
+		  ORDER BY similarity(lower(f_unaccent(word)), lower(f_unaccent($2)))
+		    DESC
+		  LIMIT $5
+		) AS candidates
+		WHERE distance <= $3
+		ORDER BY distance, word
+		LIMIT $4`,
+		languageId, word, maxDistance, limit, suggestionCandidates,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	suggestions := make([]*Suggestion, 0)
+	for rows.Next() {
+		suggestion := &Suggestion{}
+		err = rows.Scan(&suggestion.Id, &suggestion.Text,
+			&suggestion.LanguageId, &suggestion.Distance)
+		if err != nil {
+			return nil, err
+		}
+		suggestions = append(suggestions, suggestion)
+	}
+	return suggestions, nil
+}
+
+// escapeLike escapes the wildcards of a LIKE pattern in s.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// queryWords returns the words selected by a query. The query should select
+// the id, text, and language id of each word.
+func (db *PsqlDB) queryWords(query string, args ...interface{}) ([]*Word, error) {
+	rows, err := db.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -96,14 +195,11 @@ func (db *PsqlDB) GetWords() ([]*Word, error) {
 }
 
 // GetWord returns from the database a word identified by id.
-// TODO: This won't work for multiple languages.
 func (db *PsqlDB) GetWord(id int) (*Word, error) {
 	word := &Word{Id: id}
 	err := db.QueryRow(`
-		SELECT words.word, lang_id
-		FROM verb_forms
-		JOIN words on words.id = verb_forms.word_id
-		WHERE word_id = $1`,
+		SELECT word, lang_id FROM words
+		WHERE id = $1`,
 		id,
 	).Scan(&word.Text, &word.LanguageId)
 
@@ -113,6 +209,22 @@ func (db *PsqlDB) GetWord(id int) (*Word, error) {
 	return word, nil
 }
 
+// FindWord returns from the database the word of a language that is
+// spelled text.
+func (db *PsqlDB) FindWord(languageId int, text string) (*Word, error) {
+	word := &Word{Text: text, LanguageId: languageId}
+	err := db.QueryRow(`
+		SELECT id FROM words
+		WHERE lang_id = $1 AND word = $2`,
+		languageId, text,
+	).Scan(&word.Id)
+
+	if err != nil {
+		return nil, err
+	}
+	return word, nil
+}
+
 // User models a user account as found in the database.
 type User struct {
 	// A UUID for the user
@@ -131,12 +243,17 @@ type User struct {
 	CreatedAt time.Time
 }
 
-// GetUsers returns a slice of all users in the database.
-func (db *PsqlDB) GetUsers() ([]*User, error) {
+// GetUsers returns a page of the users in the database. If
+// opts.LanguageId is not 0, only users who target that language are listed.
+func (db *PsqlDB) GetUsers(opts ListOptions) ([]*User, error) {
+	page, args := opts.pageClause(UserSortFields, 1)
 	rows, err := db.Query(`
 		SELECT id, role_id, name, target_language_id, created_at
-		FROM users;
-	`)
+		FROM users
+		WHERE ($1 = 0 OR target_language_id = $1)
+		`+page,
+		append([]interface{}{opts.LanguageId}, args...)...,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -182,23 +299,6 @@ func (db *PsqlDB) CreateUser(name, password string) (string, error) {
 	return userId, err
 }
 
-// IsAdmin returns true if the user has administrator pivileges.
-func (db *PsqlDB) IsAdmin(userId string) bool {
-	var role string
-	err := db.QueryRow(`
-		SELECT role FROM roles
-		WHERE id = (
-			SELECT role_id FROM users
-			WHERE id = $1
-		)`, userId,
-	).Scan(&role)
-	if err != nil {
-		return false
-	}
-
-	return role == "admin"
-}
-
 // GetUserId returns the id of a user with the matching username and password or
 // an empty string if the credentials are invalid.
 // password should be plaintext; the database will handle any hashing/salting.
@@ -213,11 +313,159 @@ func (db *PsqlDB) GetUserId(username, password string) string {
 	return id
 }
 
-// Conjugation table stores the present and past tense forms of an infintive.
+var (
+	// ErrNameTaken is returned when a user is renamed to the name of another.
+	ErrNameTaken = errors.New("user name is taken")
+
+	// ErrUnknownLanguage is returned when a user targets a language that
+	// doesn't exist.
+	ErrUnknownLanguage = errors.New("language does not exist")
+
+	// ErrWrongPassword is returned when a password change doesn't give the
+	// current password.
+	ErrWrongPassword = errors.New("current password is incorrect")
+)
+
+// UserChanges lists the fields of a user to update. Nil fields are left
+// as they are. A TargetLanguageId that is not Valid clears the target
+// language.
+type UserChanges struct {
+	Name             *string
+	TargetLanguageId *sql.NullInt64
+}
+
+// UpdateUser changes the fields of a user and returns the result.
+// sql.ErrNoRows is returned if the user doesn't exist.
+func (db *PsqlDB) UpdateUser(id string, changes UserChanges) (*User, error) {
+	if lang := changes.TargetLanguageId; lang != nil && lang.Valid {
+		if _, err := db.GetLanguage(int(lang.Int64)); err == sql.ErrNoRows {
+			return nil, ErrUnknownLanguage
+		} else if err != nil {
+			return nil, err
+		}
+	}
+
+	var name sql.NullString
+	if changes.Name != nil {
+		name = sql.NullString{String: *changes.Name, Valid: true}
+	}
+	var languageId sql.NullInt64
+	if changes.TargetLanguageId != nil {
+		languageId = *changes.TargetLanguageId
+	}
+
+	user := &User{}
+	err := db.QueryRow(`
+		UPDATE users
+		SET name = COALESCE($2, name),
+		    target_language_id = CASE WHEN $3 THEN $4 ELSE target_language_id END
+		WHERE id = $1
+		RETURNING id, role_id, name, target_language_id, created_at`,
+		id, name, changes.TargetLanguageId != nil, languageId,
+	).Scan(&user.Id, &user.RoleId, &user.Name, &user.TargetLanguageId,
+		&user.CreatedAt)
+
+	if isViolation(err, uniqueViolation) {
+		return nil, ErrNameTaken
+	} else if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// ChangePassword replaces the password of a user if oldPassword is their
+// current one, and revokes the user's tokens so that anyone who knew the old
+// password is signed out. Passwords should be plaintext, as in CreateUser.
+func (db *PsqlDB) ChangePassword(id, oldPassword, newPassword string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		UPDATE users
+		SET password = crypt($3, gen_salt('bf', 8))
+		WHERE id = $1
+		AND   password = crypt($2, password)`,
+		id, oldPassword, newPassword,
+	)
+	if err != nil {
+		return err
+	}
+	if count, err := result.RowsAffected(); err != nil {
+		return err
+	} else if count == 0 {
+		return ErrWrongPassword
+	}
+
+	if err = revokeTokens(tx, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// DeleteUser removes a user along with their drills, cards, and practice
+// results. sql.ErrNoRows is returned if the user doesn't exist.
+func (db *PsqlDB) DeleteUser(id string) error {
+	result, err := db.Exec(`DELETE FROM users WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if count, err := result.RowsAffected(); err != nil {
+		return err
+	} else if count == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// Conjugation table stores the present and past tense forms of an infintive
+// in each grammatical mood.
 type ConjugationTable struct {
 	Infinitive string
-	Present    *TenseInflection
-	Past       *TenseInflection
+	// The verb used with the past participle to build perfect tenses
+	// (e.g., 'hebben' or 'zijn'). It is empty if unknown.
+	Auxiliary   string
+	NonFinite   *NonFiniteForms
+	Indicative  *MoodInflection
+	Subjunctive *MoodInflection
+	Imperative  *MoodInflection
+
+	// Compound tenses are only present if they were requested.
+	Perfect     *TenseInflection `json:",omitempty"`
+	Pluperfect  *TenseInflection `json:",omitempty"`
+	Future      *TenseInflection `json:",omitempty"`
+	Conditional *TenseInflection `json:",omitempty"`
+
+	// The paths (e.g., 'Indicative.Past.First') of the forms that were
+	// generated by rules because they were missing from the imported data
+	Generated []string `json:",omitempty"`
+}
+
+// NonFiniteForms stores the forms of a verb that are not conjugated for
+// person or number. Unknown forms are empty.
+type NonFiniteForms struct {
+	PresentParticiple string
+	PastParticiple    string
+	Gerund            string
+}
+
+// MoodInflection stores the forms of a verb in a certain mood.
+// Moods that lack a tense (e.g., the imperative has no past) leave its
+// inflection empty.
+type MoodInflection struct {
+	Present *TenseInflection
+	Past    *TenseInflection
+}
+
+// NewMoodInflection creates a MoodInflection with an empty TenseInflection
+// for each tense.
+func NewMoodInflection() *MoodInflection {
+	return &MoodInflection{
+		Present: NewTenseInflection(),
+		Past:    NewTenseInflection(),
+	}
 }
 
 // TenseInflection stores the forms of a verb in a certain tense.
@@ -225,7 +473,14 @@ type TenseInflection struct {
 	First  []string
 	Second []string
 	Third  []string
+	// Plural forms that every person shares
 	Plural []string
+
+	// Plural forms of languages that tell persons apart in the plural
+	// (e.g., German 'wir machen' but 'ihr macht')
+	PluralFirst  []string `json:",omitempty"`
+	PluralSecond []string `json:",omitempty"`
+	PluralThird  []string `json:",omitempty"`
 }
 
 // NewTenseInflection creates a TenseInflection with empty arrays for
@@ -245,8 +500,19 @@ func NewTenseInflection() *TenseInflection {
 // Consume places word in the appropriate grammatical category in tense.
 func (tense *TenseInflection) Consume(word string, person Person, number Number) {
 	if number == Plural {
-		// plural won't have a person
-		tense.Plural = append(tense.Plural, word)
+		// Plural forms only have a person if the language tells them apart.
+		if person == 0 {
+			tense.Plural = append(tense.Plural, word)
+		}
+		if person&First != 0 {
+			tense.PluralFirst = append(tense.PluralFirst, word)
+		}
+		if person&Second != 0 {
+			tense.PluralSecond = append(tense.PluralSecond, word)
+		}
+		if person&Third != 0 {
+			tense.PluralThird = append(tense.PluralThird, word)
+		}
 	} else if number == Singular {
 		if person&First != 0 {
 			tense.First = append(tense.First, word)
@@ -278,60 +544,109 @@ const (
 	Plural   Number = 2
 )
 
-// GetConjugationTable retrieves a tense inflection for word.
-func (db *PsqlDB) GetConjugationTable(word string) (*ConjugationTable, error) {
-	inf, infId, err := db.GetInfinitive(word)
+// GetConjugationTable retrieves a tense inflection for a word of a language.
+// Compound tenses are only built if they are listed in compounds.
+func (db *PsqlDB) GetConjugationTable(languageId int, word string,
+	compounds []CompoundTense) (*ConjugationTable, error) {
+	inf, infId, err := db.GetInfinitive(languageId, word)
 	if err == sql.ErrNoRows {
 		return nil, errors.New("word " + word + " does not exist")
 	} else if err != nil {
 		return nil, err
 	}
 
+	moods, err := db.getMoodInflections(infId)
+	if err != nil {
+		return nil, err
+	}
+
+	table := &ConjugationTable{
+		Infinitive:  inf,
+		NonFinite:   &NonFiniteForms{},
+		Indicative:  moods[0],
+		Subjunctive: moods[1],
+		Imperative:  moods[2],
+	}
+	if err = db.getNonFiniteForms(infId, table); err != nil {
+		return nil, err
+	}
+	// Compound tenses may use a generated participle, so this comes first.
+	if err = db.fillRegularForms(languageId, infId, table); err != nil {
+		return nil, err
+	}
+	return table, db.addCompoundTenses(languageId, table, compounds)
+}
+
+// getMoodInflections retrieves the forms of an infinitive in each mood.
+// The moods are ordered by id: indicative, subjunctive, and imperative.
+func (db *PsqlDB) getMoodInflections(infId int) ([]*MoodInflection, error) {
 	// We won't read person into a nullable type, so it is important that the
 	// value is read last. If, for example, person was the first column listed
 	// and it was null, the other columns would be ignored and Go would give
 	// them zero values.
 	rows, err := db.Query(`
-		SELECT words.word, num, tense_id, person
+		SELECT words.word, num, tense_id, mood_id, person
 		FROM verb_forms
 		JOIN words on words.id = verb_forms.word_id
 		WHERE inf_id = $1`,
 		infId,
 	)
-	defer rows.Close()
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
-	tenses := []*TenseInflection{NewTenseInflection(), NewTenseInflection()}
+	moods := []*MoodInflection{
+		NewMoodInflection(), NewMoodInflection(), NewMoodInflection(),
+	}
 	for rows.Next() {
 		var form string
 		var person Person
 		var number Number
-		var tense int
+		var tense, mood int
 
-		rows.Scan(&form, &number, &tense, &person)
-		tense--
-		tenses[tense].Consume(form, person, number)
+		rows.Scan(&form, &number, &tense, &mood, &person)
+		tenses := []*TenseInflection{moods[mood-1].Present, moods[mood-1].Past}
+		tenses[tense-1].Consume(form, person, number)
 	}
+	return moods, nil
+}
 
-	return &ConjugationTable{
-		Infinitive: inf,
-		Present:    tenses[0],
-		Past:       tenses[1],
-	}, nil
+// getNonFiniteForms fills in the auxiliary and non-finite forms of table
+// using the infinitive identified by infId.
+func (db *PsqlDB) getNonFiniteForms(infId int, table *ConjugationTable) error {
+	err := db.QueryRow(`
+		SELECT COALESCE(aux.word, ''), COALESCE(pres.word, ''),
+		       COALESCE(past.word, ''), COALESCE(gerund.word, '')
+		FROM infinitives
+		LEFT JOIN words aux    on aux.id    = infinitives.aux_id
+		LEFT JOIN words pres   on pres.id   = infinitives.pres_ptc_id
+		LEFT JOIN words past   on past.id   = infinitives.past_ptc_id
+		LEFT JOIN words gerund on gerund.id = infinitives.gerund_id
+		WHERE word_id = $1`,
+		infId,
+	).Scan(&table.Auxiliary, &table.NonFinite.PresentParticiple,
+		&table.NonFinite.PastParticiple, &table.NonFinite.Gerund)
+
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	return err
 }
 
-// GetInfinitive retrieves the word and id of the verb form's infinitive.
-func (db *PsqlDB) GetInfinitive(verbForm string) (string, int, error) {
+// GetInfinitive retrieves the word and id of the infinitive of a verb form
+// in a language.
+func (db *PsqlDB) GetInfinitive(languageId int, verbForm string) (string, int, error) {
 	var infinitive string
 	var id int
 	err := db.QueryRow(`
 		SELECT words.word, inf_id
 		FROM verb_forms
 		JOIN words on words.id = verb_forms.inf_id
-		WHERE word_id = (SELECT id FROM words WHERE word = $1)`,
-		verbForm,
+		WHERE word_id = (
+		  SELECT id FROM words
+		  WHERE lang_id = $1 AND word = $2)`,
+		languageId, verbForm,
 	).Scan(&infinitive, &id)
 
 	if err != nil {
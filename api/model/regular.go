@@ -0,0 +1,262 @@
+package model
+
+import (
+	"errors"
+	"mutably/api/model/rules"
+	"strings"
+)
+
+// ErrNoRules is returned when a language has no conjugation rules.
+var ErrNoRules = errors.New("language has no conjugation rules")
+
+// regularRules maps the lowercase names of languages to the rules that
+// conjugate their regular (e.g., Dutch weak) verbs.
+var regularRules = map[string]func(string) (*rules.Forms, error){
+	"dutch": rules.DutchWeak,
+}
+
+// cell is one slot of a conjugation table paired with the form that rules
+// give for it. Finite slots hold a list of forms and non-finite ones a
+// single form, so exactly one of forms and form is set.
+type cell struct {
+	// The JSON path of the slot (e.g., 'Indicative.Past.First')
+	path string
+
+	forms *[]string
+	form  *string
+
+	// The form that the rules generate
+	rule string
+}
+
+// known returns the imported forms of the slot.
+func (c cell) known() []string {
+	if c.forms != nil {
+		return *c.forms
+	}
+	if *c.form != "" {
+		return []string{*c.form}
+	}
+	return nil
+}
+
+// fill puts the generated form in the slot.
+func (c cell) fill() {
+	if c.forms != nil {
+		*c.forms = append(*c.forms, c.rule)
+	} else {
+		*c.form = c.rule
+	}
+}
+
+// regularCells pairs the slots of table with the forms that rules generated.
+func regularCells(table *ConjugationTable, forms *rules.Forms) []cell {
+	present, past := table.Indicative.Present, table.Indicative.Past
+	return []cell{
+		{path: "Indicative.Present.First", forms: &present.First,
+			rule: forms.PresentFirst},
+		{path: "Indicative.Present.Second", forms: &present.Second,
+			rule: forms.PresentSecond},
+		{path: "Indicative.Present.Third", forms: &present.Third,
+			rule: forms.PresentThird},
+		{path: "Indicative.Present.Plural", forms: &present.Plural,
+			rule: forms.PresentPlural},
+		{path: "Indicative.Past.First", forms: &past.First,
+			rule: forms.PastSingular},
+		{path: "Indicative.Past.Second", forms: &past.Second,
+			rule: forms.PastSingular},
+		{path: "Indicative.Past.Third", forms: &past.Third,
+			rule: forms.PastSingular},
+		{path: "Indicative.Past.Plural", forms: &past.Plural,
+			rule: forms.PastPlural},
+		{path: "Imperative.Present.Second",
+			forms: &table.Imperative.Present.Second, rule: forms.Imperative},
+		{path: "NonFinite.PresentParticiple",
+			form: &table.NonFinite.PresentParticiple,
+			rule: forms.PresentParticiple},
+		{path: "NonFinite.PastParticiple",
+			form: &table.NonFinite.PastParticiple, rule: forms.PastParticiple},
+		{path: "NonFinite.Gerund", form: &table.NonFinite.Gerund,
+			rule: forms.Gerund},
+	}
+}
+
+// contains reports whether forms includes form, ignoring case.
+func contains(forms []string, form string) bool {
+	for _, f := range forms {
+		if strings.EqualFold(f, form) {
+			return true
+		}
+	}
+	return false
+}
+
+// fillRegularForms uses the rules of a language to fill the empty slots of
+// the table of a regular verb. The paths of the filled slots are recorded
+// in table.Generated.
+//
+// Only verbs that were classed as weak at import are filled, and only if
+// their imported forms agree with the rules.
+func (db *PsqlDB) fillRegularForms(languageId, infId int,
+	table *ConjugationTable) error {
+	var language, class string
+	err := db.QueryRow(`
+		SELECT lower(languages.name), COALESCE(verb_classes.class, '')
+		FROM languages
+		LEFT JOIN infinitives
+		  on  infinitives.lang_id = languages.id
+		  AND infinitives.word_id = $2
+		LEFT JOIN verb_classes on verb_classes.id = infinitives.class_id
+		WHERE languages.id = $1`,
+		languageId, infId,
+	).Scan(&language, &class)
+	if err != nil {
+		return err
+	}
+
+	conjugate, ok := regularRules[language]
+	if !ok || class != "weak" {
+		return nil
+	}
+	forms, err := conjugate(table.Infinitive)
+	if err != nil {
+		// Not a regular verb after all
+		return nil
+	}
+
+	cells := regularCells(table, forms)
+	for _, c := range cells {
+		if known := c.known(); len(known) != 0 && !contains(known, c.rule) {
+			return nil
+		}
+	}
+	for _, c := range cells {
+		if len(c.known()) == 0 {
+			c.fill()
+			table.Generated = append(table.Generated, c.path)
+		}
+	}
+	return nil
+}
+
+// RuleAccuracy compares the forms that rules generate for the weak verbs of
+// a language with the forms that were imported.
+type RuleAccuracy struct {
+	// The number of weak verbs compared
+	Verbs int `json:"verbs"`
+
+	// The number of slots that had imported forms and how many of those
+	// included the generated form
+	Checked int `json:"checked"`
+	Correct int `json:"correct"`
+
+	// Counts for each slot, keyed by path (e.g., 'Indicative.Past.First')
+	Cells map[string]*CellAccuracy `json:"cells"`
+
+	// A sample of the slots where the rules were wrong
+	Mismatches []*Mismatch `json:"mismatches"`
+}
+
+// CellAccuracy counts the checked and correct forms of one slot.
+type CellAccuracy struct {
+	Checked int `json:"checked"`
+	Correct int `json:"correct"`
+}
+
+// Mismatch is a generated form that disagrees with imported ones.
+type Mismatch struct {
+	Infinitive string   `json:"infinitive"`
+	Path       string   `json:"path"`
+	Generated  string   `json:"generated"`
+	Imported   []string `json:"imported"`
+}
+
+// The most mismatches that a RuleAccuracy lists
+const maxMismatches = 50
+
+// MeasureRules tests the rules of a language against its imported weak
+// verbs. A non-nil error is returned if the language has no rules.
+func (db *PsqlDB) MeasureRules(languageId int) (*RuleAccuracy, error) {
+	language, err := db.GetLanguage(languageId)
+	if err != nil {
+		return nil, err
+	}
+	conjugate, ok := regularRules[strings.ToLower(language.Name)]
+	if !ok {
+		return nil, ErrNoRules
+	}
+
+	infinitives, err := db.getWeakInfinitives(languageId)
+	if err != nil {
+		return nil, err
+	}
+
+	accuracy := &RuleAccuracy{
+		Cells:      make(map[string]*CellAccuracy),
+		Mismatches: make([]*Mismatch, 0),
+	}
+	for _, infinitive := range infinitives {
+		forms, err := conjugate(infinitive.Text)
+		if err != nil {
+			continue
+		}
+
+		moods, err := db.getMoodInflections(infinitive.Id)
+		if err != nil {
+			return nil, err
+		}
+		table := &ConjugationTable{
+			Infinitive:  infinitive.Text,
+			NonFinite:   &NonFiniteForms{},
+			Indicative:  moods[0],
+			Subjunctive: moods[1],
+			Imperative:  moods[2],
+		}
+		if err = db.getNonFiniteForms(infinitive.Id, table); err != nil {
+			return nil, err
+		}
+
+		accuracy.Verbs++
+		for _, c := range regularCells(table, forms) {
+			known := c.known()
+			if len(known) == 0 {
+				continue
+			}
+
+			counts, ok := accuracy.Cells[c.path]
+			if !ok {
+				counts = &CellAccuracy{}
+				accuracy.Cells[c.path] = counts
+			}
+			accuracy.Checked++
+			counts.Checked++
+			if contains(known, c.rule) {
+				accuracy.Correct++
+				counts.Correct++
+			} else if len(accuracy.Mismatches) < maxMismatches {
+				accuracy.Mismatches = append(accuracy.Mismatches, &Mismatch{
+					Infinitive: infinitive.Text,
+					Path:       c.path,
+					Generated:  c.rule,
+					Imported:   known,
+				})
+			}
+		}
+	}
+	return accuracy, nil
+}
+
+// getWeakInfinitives returns the infinitives of the weak verbs of a
+// language in alphabetical order.
+func (db *PsqlDB) getWeakInfinitives(languageId int) ([]*Word, error) {
+	return db.queryWords(`
+		SELECT words.id, words.word, words.lang_id
+		FROM infinitives
+		JOIN words        on words.id = infinitives.word_id
+		JOIN verb_classes on verb_classes.id = infinitives.class_id
+		WHERE infinitives.lang_id = $1
+		AND   verb_classes.class = 'weak'
+		ORDER BY words.word`,
+		languageId,
+	)
+}
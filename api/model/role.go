@@ -0,0 +1,117 @@
+package model
+
+import (
+	"database/sql"
+	"errors"
+)
+
+var (
+	// ErrUnknownRole is returned for roles that don't exist.
+	ErrUnknownRole = errors.New("role does not exist")
+
+	// ErrLastAdmin is returned when the only admin would lose their role.
+	ErrLastAdmin = errors.New("the last admin can't lose their role")
+)
+
+// Role names a set of permissions that a user has.
+type Role string
+
+const (
+	RoleUser  Role = "user"
+	RoleAdmin Role = "admin"
+)
+
+// roleRanks orders roles so that each has the permissions of those below it.
+var roleRanks = map[Role]int{RoleUser: 1, RoleAdmin: 2}
+
+// Includes reports whether r has every permission of other. Unknown roles
+// include nothing.
+func (r Role) Includes(other Role) bool {
+	rank, ok := roleRanks[r]
+	return ok && rank >= roleRanks[other]
+}
+
+// GetRoles returns the names of all roles.
+func (db *PsqlDB) GetRoles() ([]Role, error) {
+	rows, err := db.Query(`SELECT role FROM roles ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var roles []Role
+	for rows.Next() {
+		var role Role
+		if err = rows.Scan(&role); err != nil {
+			return nil, err
+		}
+		roles = append(roles, role)
+	}
+	return roles, rows.Err()
+}
+
+func (db *PsqlDB) GetUserRole(userId string) (Role, error) {
+	var role Role
+	err := db.QueryRow(`
+		SELECT roles.role FROM users
+		JOIN roles on roles.id = users.role_id
+		WHERE users.id = $1`,
+		userId,
+	).Scan(&role)
+	return role, err
+}
+
+// SetUserRole gives a user a role and returns the result. The user's access
+// tokens are revoked so that none claim the old role; their refresh tokens
+// still work. sql.ErrNoRows is returned if the user doesn't exist.
+func (db *PsqlDB) SetUserRole(userId string, role Role) (*User, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var roleId int
+	err = tx.QueryRow(`SELECT id FROM roles WHERE role = $1`, role).Scan(&roleId)
+	if err == sql.ErrNoRows {
+		return nil, ErrUnknownRole
+	} else if err != nil {
+		return nil, err
+	}
+
+	// Admins are locked so that two can't demote each other at once.
+	if role != RoleAdmin {
+		var admins int
+		var isAdmin bool
+		err = tx.QueryRow(`
+			SELECT count(*), COALESCE(bool_or(admins.id = $1), false)
+			FROM (
+			  SELECT users.id FROM users
+			  JOIN roles on roles.id = users.role_id
+			  WHERE roles.role = $2
+			  FOR UPDATE OF users
+			) AS admins`,
+			userId, RoleAdmin,
+		).Scan(&admins, &isAdmin)
+		if err != nil {
+			return nil, err
+		}
+		if admins == 1 && isAdmin {
+			return nil, ErrLastAdmin
+		}
+	}
+
+	user := &User{}
+	err = tx.QueryRow(`
+		UPDATE users
+		SET role_id = $2, token_version = token_version + 1
+		WHERE id = $1
+		RETURNING id, role_id, name, target_language_id, created_at`,
+		userId, roleId,
+	).Scan(&user.Id, &user.RoleId, &user.Name, &user.TargetLanguageId,
+		&user.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return user, tx.Commit()
+}
@@ -0,0 +1,141 @@
+// Package rules generates verb forms from spelling rules. It is used to fill
+// gaps in the imported data, so it only needs to handle regular verbs.
+package rules
+
+import (
+	"errors"
+	"strings"
+)
+
+// Forms stores the regular forms of a verb.
+type Forms struct {
+	Infinitive string
+
+	PresentFirst  string
+	PresentSecond string
+	PresentThird  string
+	PresentPlural string
+
+	// The past tense has one singular form for every person.
+	PastSingular string
+	PastPlural   string
+
+	// The singular imperative
+	Imperative string
+
+	PresentParticiple string
+	PastParticiple    string
+	Gerund            string
+}
+
+// Prefixes that are never stressed. Verbs that begin with them take no 'ge'
+// in the past participle (e.g., 'betaald', not 'gebetaald').
+var dutchUnstressedPrefixes = []string{"be", "ge", "her", "ont", "ver", "er"}
+
+// DutchWeak conjugates a Dutch weak verb from its infinitive. A non-nil error
+// is returned if infinitive doesn't end in -en, as all weak verbs do.
+func DutchWeak(infinitive string) (*Forms, error) {
+	infinitive = strings.ToLower(infinitive)
+	if len(infinitive) < 4 || !strings.HasSuffix(infinitive, "en") {
+		return nil, errors.New(infinitive + " is not a weak verb infinitive")
+	}
+
+	prefix := dutchPrefix(infinitive)
+	base := strings.TrimSuffix(infinitive, "en")
+	stem := prefix + dutchStem(base[len(prefix):])
+
+	// 't kofschip: stems that end in a voiceless consonant take -te(n) and
+	// -t; the rest take -de(n) and -d. The spelling of the infinitive is
+	// used because the stem spells voiced v and z as f and s.
+	ending := "d"
+	if strings.ContainsRune("tkfschpx", rune(base[len(base)-1])) {
+		ending = "t"
+	}
+
+	forms := &Forms{
+		Infinitive:        infinitive,
+		PresentFirst:      stem,
+		PresentSecond:     addEnding(stem, "t"),
+		PresentThird:      addEnding(stem, "t"),
+		PresentPlural:     infinitive,
+		PastSingular:      stem + ending + "e",
+		PastPlural:        stem + ending + "en",
+		Imperative:        stem,
+		PresentParticiple: infinitive + "d",
+		PastParticiple:    addEnding(stem, ending),
+		Gerund:            infinitive,
+	}
+	if prefix == "" {
+		forms.PastParticiple = addGe(forms.PastParticiple)
+	}
+	return forms, nil
+}
+
+// dutchPrefix returns the unstressed prefix of infinitive, if any. Short
+// verbs are assumed to have none, since 'be' in 'bellen' is not a prefix.
+func dutchPrefix(infinitive string) string {
+	for _, prefix := range dutchUnstressedPrefixes {
+		if strings.HasPrefix(infinitive, prefix) &&
+			len(infinitive)-len(prefix) >= 5 {
+			return prefix
+		}
+	}
+	return ""
+}
+
+// dutchStem spells the stem of a verb from its infinitive without -en. The
+// stem ends in a closed syllable, so a vowel that was long because its
+// syllable was open is doubled (e.g., 'maken' to 'maak'), a doubled
+// consonant is made single (e.g., 'zeggen' to 'zeg'), and a final v or z is
+// written f or s (e.g., 'leven' to 'leef').
+func dutchStem(base string) string {
+	n := len(base)
+	switch {
+	case n >= 2 && base[n-1] == base[n-2] && !isVowel(base[n-1]):
+		base = base[:n-1]
+	case n >= 2 && !isVowel(base[n-1]) && strings.IndexByte("aeou", base[n-2]) >= 0 &&
+		(n == 2 || !isVowel(base[n-3])) && !isSchwa(base, n-2):
+		base = base[:n-1] + base[n-2:]
+	}
+
+	switch base[len(base)-1] {
+	case 'v':
+		base = base[:len(base)-1] + "f"
+	case 'z':
+		base = base[:len(base)-1] + "s"
+	}
+	return base
+}
+
+// isSchwa guesses whether the e at i is unstressed. Only the first syllable
+// of a stem is taken to be stressed (e.g., 'teken' and 'wandel').
+func isSchwa(base string, i int) bool {
+	return base[i] == 'e' && strings.IndexAny(base[:i], "aeiou") >= 0
+}
+
+func isVowel(c byte) bool {
+	return strings.IndexByte("aeiou", c) >= 0
+}
+
+// addEnding adds a t or d ending to stem unless stem already ends with it
+// (e.g., 'zet', not 'zett').
+func addEnding(stem, ending string) string {
+	if strings.HasSuffix(stem, ending) {
+		return stem
+	}
+	return stem + ending
+}
+
+// addGe adds the participle prefix to word. A trema splits the vowels when
+// they would otherwise be read as one sound (e.g., 'geëindigd').
+func addGe(word string) string {
+	switch word[0] {
+	case 'e':
+		return "geë" + word[1:]
+	case 'i':
+		return "geï" + word[1:]
+	case 'u':
+		return "geü" + word[1:]
+	}
+	return "ge" + word
+}
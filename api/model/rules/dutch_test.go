@@ -0,0 +1,67 @@
+package rules_test
+
+import (
+	"mutably/api/model/rules"
+	"testing"
+)
+
+// DutchWeak should choose -te or -de by 't kofschip and respell the stem.
+func TestDutchWeak(t *testing.T) {
+	tests := []struct {
+		infinitive, present, third, past, participle string
+	}{
+		{"werken", "werk", "werkt", "werkte", "gewerkt"},
+		{"maken", "maak", "maakt", "maakte", "gemaakt"},
+		{"zeggen", "zeg", "zegt", "zegde", "gezegd"},
+		{"zetten", "zet", "zet", "zette", "gezet"},
+		{"branden", "brand", "brandt", "brandde", "gebrand"},
+		{"leven", "leef", "leeft", "leefde", "geleefd"},
+		{"reizen", "reis", "reist", "reisde", "gereisd"},
+		{"fietsen", "fiets", "fietst", "fietste", "gefietst"},
+		{"tekenen", "teken", "tekent", "tekende", "getekend"},
+		{"betalen", "betaal", "betaalt", "betaalde", "betaald"},
+		{"bellen", "bel", "belt", "belde", "gebeld"},
+		{"eindigen", "eindig", "eindigt", "eindigde", "geëindigd"},
+	}
+
+	for _, test := range tests {
+		forms, err := rules.DutchWeak(test.infinitive)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		actual := []string{forms.PresentFirst, forms.PresentThird,
+			forms.PastSingular, forms.PastParticiple}
+		expected := []string{test.present, test.third, test.past,
+			test.participle}
+		for i := range expected {
+			if actual[i] != expected[i] {
+				t.Errorf("%s: expected %v, got %v", test.infinitive, expected,
+					actual)
+				break
+			}
+		}
+	}
+}
+
+// DutchWeak should derive the forms that don't depend on the stem.
+func TestDutchWeak_regularForms(t *testing.T) {
+	forms, err := rules.DutchWeak("Werken")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if forms.PresentPlural != "werken" || forms.PastPlural != "werkten" ||
+		forms.PresentParticiple != "werkend" || forms.Imperative != "werk" ||
+		forms.Gerund != "werken" {
+		t.Error("Unexpected forms of 'werken':", forms)
+	}
+}
+
+// DutchWeak should reject words that can't be weak verb infinitives.
+func TestDutchWeak_invalid(t *testing.T) {
+	for _, word := range []string{"", "en", "gaan", "werk"} {
+		if _, err := rules.DutchWeak(word); err == nil {
+			t.Errorf("Expected '%s' to be rejected", word)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+// Package scheduler decides when learners should review what they have
+// studied. It implements the SuperMemo 2 (SM-2) algorithm.
+package scheduler
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+// Grade rates how well a learner recalled an item, from 0 (complete
+// blackout) to 5 (perfect response). Grades below 3 count as forgotten.
+type Grade int
+
+const (
+	MinGrade  Grade = 0
+	MaxGrade  Grade = 5
+	PassGrade Grade = 3
+)
+
+// ErrInvalidGrade is returned for grades outside of [MinGrade, MaxGrade].
+var ErrInvalidGrade = errors.New("grade must be between 0 and 5")
+
+// The ease of a new card and the lowest that any card can reach
+const (
+	initialEase = 2.5
+	minEase     = 1.3
+)
+
+// Card is the review state of one item.
+type Card struct {
+	// The number of reviews in a row that passed
+	Repetitions int
+
+	// The number of days between the last review and the next
+	Interval int
+
+	// How quickly the interval grows after a passing review
+	Ease float64
+
+	// The number of times the card was forgotten after being learned
+	Lapses int
+
+	// When the card should next be reviewed
+	Due time.Time
+}
+
+// NewCard returns the state of an item that was never reviewed. It is due
+// at once.
+func NewCard(now time.Time) Card {
+	return Card{Ease: initialEase, Due: now}
+}
+
+// Review returns the state of card after it was reviewed at now with grade.
+func Review(card Card, grade Grade, now time.Time) (Card, error) {
+	if grade < MinGrade || grade > MaxGrade {
+		return card, ErrInvalidGrade
+	}
+
+	if grade < PassGrade {
+		if card.Repetitions > 0 {
+			card.Lapses++
+		}
+		card.Repetitions = 0
+		card.Interval = 1
+	} else {
+		switch card.Repetitions {
+		case 0:
+			card.Interval = 1
+		case 1:
+			card.Interval = 6
+		default:
+			// Rounded by hand; math.Round is newer than the Go we build with.
+			interval := float64(card.Interval) * card.Ease
+			card.Interval = int(math.Floor(interval + 0.5))
+		}
+		card.Repetitions++
+	}
+
+	// The ease changes after every review, even failed ones.
+	q := float64(MaxGrade - grade)
+	card.Ease = math.Max(minEase, card.Ease+0.1-q*(0.08+q*0.02))
+
+	card.Due = now.AddDate(0, 0, card.Interval)
+	return card, nil
+}
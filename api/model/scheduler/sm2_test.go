@@ -0,0 +1,65 @@
+package scheduler_test
+
+import (
+	"mutably/api/model/scheduler"
+	"testing"
+	"time"
+)
+
+var start = time.Date(2018, 1, 1, 9, 0, 0, 0, time.UTC)
+
+// Review should space passing reviews 1, 6, and then ease times the last
+// interval days apart.
+func TestReview_pass(t *testing.T) {
+	card := scheduler.NewCard(start)
+	if !card.Due.Equal(start) {
+		t.Error("Expected a new card to be due at once")
+	}
+
+	now := start
+	for _, interval := range []int{1, 6, 15, 38} {
+		var err error
+		card, err = scheduler.Review(card, 4, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if card.Interval != interval {
+			t.Fatalf("Expected interval %d, got %d", interval, card.Interval)
+		}
+		if !card.Due.Equal(now.AddDate(0, 0, interval)) {
+			t.Errorf("Expected card to be due %d days after review", interval)
+		}
+		now = card.Due
+	}
+	if card.Ease != 2.5 || card.Repetitions != 4 {
+		t.Error("Grade 4 should keep ease at 2.5, got", card.Ease)
+	}
+}
+
+// Review should restart a forgotten card and make it harder, but never below
+// the minimum ease.
+func TestReview_fail(t *testing.T) {
+	card := scheduler.NewCard(start)
+	card, _ = scheduler.Review(card, 5, start)
+	card, _ = scheduler.Review(card, 5, card.Due)
+
+	for i := 0; i < 10; i++ {
+		card, _ = scheduler.Review(card, 0, card.Due)
+	}
+	if card.Repetitions != 0 || card.Interval != 1 || card.Lapses != 1 {
+		t.Error("Expected a forgotten card to start over, got", card)
+	}
+	if card.Ease != 1.3 {
+		t.Error("Expected ease to stop at 1.3, got", card.Ease)
+	}
+}
+
+// Review should reject grades outside of 0-5.
+func TestReview_invalidGrade(t *testing.T) {
+	card := scheduler.NewCard(start)
+	for _, grade := range []scheduler.Grade{-1, 6} {
+		if _, err := scheduler.Review(card, grade, start); err == nil {
+			t.Error("Expected grade", grade, "to be rejected")
+		}
+	}
+}
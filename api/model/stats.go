@@ -0,0 +1,327 @@
+package model
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// ErrUnknownCell is returned when a practice result names a verb, tense, or
+// mood that doesn't exist.
+var ErrUnknownCell = errors.New("unknown verb, tense, or mood")
+
+// PracticeResult records whether a user gave the right form of a cell.
+type PracticeResult struct {
+	FormCell
+
+	Correct bool `json:"correct"`
+
+	// When the cell was practiced; if nil, the time it is recorded is used
+	PracticedAt *time.Time `json:"practicedAt,omitempty"`
+}
+
+// execer is implemented by both *sql.DB and *sql.Tx.
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// recordResult stores the outcome of practicing a cell that was read from
+// the database.
+func recordResult(db execer, userId string, cell *FormCell,
+	correct bool) error {
+	_, err := db.Exec(`
+		INSERT INTO practice_results (user_id, lang_id, inf_id, tense_id,
+		                              mood_id, person, num, correct)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, 0), $7, $8)`,
+		userId, cell.LanguageId, cell.infId, cell.tenseId, cell.moodId,
+		cell.person, cell.number, correct,
+	)
+	return err
+}
+
+// RecordResults stores results that a client reports for a user. The cell
+// of each is named by its language, infinitive, tense, mood (indicative if
+// empty), person, and number. Either all results are stored or, if one
+// names an unknown cell, none are.
+func (db *PsqlDB) RecordResults(userId string,
+	results []*PracticeResult) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, result := range results {
+		cell := result.FormCell
+		if cell.Mood == "" {
+			cell.Mood = "indicative"
+		}
+
+		var person sql.NullInt64
+		var number Number
+		switch {
+		case cell.Number == "plural" && cell.Person == 0:
+			number = Plural
+		case cell.Number == "singular" && cell.Person >= 1 && cell.Person <= 3:
+			number = Singular
+			person.Valid = true
+			person.Int64 = int64([]Person{First, Second, Third}[cell.Person-1])
+		default:
+			return ErrUnknownCell
+		}
+
+		inserted, err := tx.Exec(`
+			INSERT INTO practice_results (user_id, lang_id, inf_id, tense_id,
+			                              mood_id, person, num, correct,
+			                              practiced_at)
+			SELECT $1, words.lang_id, words.id, tenses.id, moods.id, $6, $7, $8,
+			       COALESCE($9, LOCALTIMESTAMP)
+			FROM words, tenses, moods
+			WHERE words.lang_id = $2
+			AND   words.word    = $3
+			AND   tenses.tense  = $4
+			AND   moods.mood    = $5`,
+			userId, cell.LanguageId, cell.Infinitive, cell.Tense, cell.Mood,
+			person, number, result.Correct, result.PracticedAt,
+		)
+		if err != nil {
+			return err
+		}
+		if count, err := inserted.RowsAffected(); err != nil {
+			return err
+		} else if count == 0 {
+			return ErrUnknownCell
+		}
+	}
+	return tx.Commit()
+}
+
+// Tally counts results and how many of them were correct.
+type Tally struct {
+	Total   int `json:"total"`
+	Correct int `json:"correct"`
+}
+
+// TenseTally is a Tally of the results in one tense and mood.
+type TenseTally struct {
+	Tense string `json:"tense"`
+	Mood  string `json:"mood"`
+	Tally
+}
+
+// PersonTally is a Tally of the results in one person and number.
+type PersonTally struct {
+	// The grammatical person (1, 2, or 3) of singular results
+	Person int    `json:"person,omitempty"`
+	Number string `json:"number"`
+	Tally
+}
+
+// MissedVerb is a verb that a user often gets wrong.
+type MissedVerb struct {
+	LanguageId int    `json:"language"`
+	Infinitive string `json:"infinitive"`
+	Misses     int    `json:"misses"`
+	Tally
+}
+
+// DayTally is a Tally of the results of one day.
+type DayTally struct {
+	// The day in the form 2006-01-02
+	Date string `json:"date"`
+	Tally
+}
+
+// Stats summarizes the practice results of a user.
+type Stats struct {
+	Tally
+
+	ByTense    []*TenseTally  `json:"byTense"`
+	ByPerson   []*PersonTally `json:"byPerson"`
+	MostMissed []*MissedVerb  `json:"mostMissed"`
+
+	// Results of each recent day that had any, oldest first
+	Daily []*DayTally `json:"daily"`
+
+	// The number of days in a row, ending today or yesterday, that had
+	// results, and the most days in a row ever
+	CurrentStreak int `json:"currentStreak"`
+	LongestStreak int `json:"longestStreak"`
+}
+
+// The number of verbs listed in Stats.MostMissed
+const mostMissedCount = 10
+
+// GetStats summarizes the practice results of a user. If languageId is not
+// 0, only results in that language are counted. Daily totals cover the last
+// days days, including today.
+func (db *PsqlDB) GetStats(userId string, languageId,
+	days int) (*Stats, error) {
+	stats := &Stats{
+		ByTense:    make([]*TenseTally, 0),
+		ByPerson:   make([]*PersonTally, 0),
+		MostMissed: make([]*MissedVerb, 0),
+		Daily:      make([]*DayTally, 0),
+	}
+
+	const filter = `WHERE r.user_id = $1 AND ($2 = 0 OR r.lang_id = $2)`
+	err := db.QueryRow(`
+		SELECT count(*), count(*) FILTER (WHERE correct)
+		FROM practice_results r `+filter,
+		userId, languageId,
+	).Scan(&stats.Total, &stats.Correct)
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.queryTallies(`
+		SELECT tenses.tense, moods.mood, count(*),
+		       count(*) FILTER (WHERE correct)
+		FROM practice_results r
+		JOIN tenses on tenses.id = r.tense_id
+		JOIN moods  on moods.id  = r.mood_id
+		`+filter+`
+		GROUP BY r.tense_id, r.mood_id, tenses.tense, moods.mood
+		ORDER BY r.tense_id, r.mood_id`,
+		[]interface{}{userId, languageId},
+		func(scan func(...interface{}) error) error {
+			t := &TenseTally{}
+			stats.ByTense = append(stats.ByTense, t)
+			return scan(&t.Tense, &t.Mood, &t.Total, &t.Correct)
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.queryTallies(`
+		SELECT COALESCE(person, 0), num, count(*),
+		       count(*) FILTER (WHERE correct)
+		FROM practice_results r `+filter+`
+		GROUP BY 1, 2
+		ORDER BY 2, 1`,
+		[]interface{}{userId, languageId},
+		func(scan func(...interface{}) error) error {
+			t := &PersonTally{}
+			var person Person
+			var number Number
+			if err := scan(&person, &number, &t.Total, &t.Correct); err != nil {
+				return err
+			}
+			cell := FormCell{person: person, number: number}
+			cell.finish()
+			t.Person, t.Number = cell.Person, cell.Number
+			stats.ByPerson = append(stats.ByPerson, t)
+			return nil
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.queryTallies(`
+		SELECT r.lang_id, words.word, count(*) FILTER (WHERE NOT correct),
+		       count(*), count(*) FILTER (WHERE correct)
+		FROM practice_results r
+		JOIN words on words.id = r.inf_id
+		`+filter+`
+		GROUP BY r.lang_id, r.inf_id, words.word
+		HAVING count(*) FILTER (WHERE NOT correct) > 0
+		ORDER BY 3 DESC, words.word
+		LIMIT $3`,
+		[]interface{}{userId, languageId, mostMissedCount},
+		func(scan func(...interface{}) error) error {
+			v := &MissedVerb{}
+			stats.MostMissed = append(stats.MostMissed, v)
+			return scan(&v.LanguageId, &v.Infinitive, &v.Misses, &v.Total,
+				&v.Correct)
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.queryTallies(`
+		SELECT to_char(practiced_at, 'YYYY-MM-DD'), count(*),
+		       count(*) FILTER (WHERE correct)
+		FROM practice_results r `+filter+`
+		AND   practiced_at >= CURRENT_DATE - ($3 - 1)
+		AND   practiced_at <  CURRENT_DATE + 1
+		GROUP BY 1
+		ORDER BY 1`,
+		[]interface{}{userId, languageId, days},
+		func(scan func(...interface{}) error) error {
+			d := &DayTally{}
+			stats.Daily = append(stats.Daily, d)
+			return scan(&d.Date, &d.Total, &d.Correct)
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return stats, db.addStreaks(userId, languageId, stats)
+}
+
+// queryTallies runs query and calls read with the scan function of each row.
+func (db *PsqlDB) queryTallies(query string, args []interface{},
+	read func(scan func(...interface{}) error) error) error {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if err = read(rows.Scan); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// addStreaks finds the current and longest streaks of days with results.
+func (db *PsqlDB) addStreaks(userId string, languageId int,
+	stats *Stats) error {
+	rows, err := db.Query(`
+		SELECT DISTINCT practiced_at::date - CURRENT_DATE
+		FROM practice_results r
+		WHERE r.user_id = $1 AND ($2 = 0 OR r.lang_id = $2)
+		ORDER BY 1 DESC`,
+		userId, languageId,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	// Days are counted relative to today (0), newest first. Results dated
+	// in the future are ignored.
+	streak, previous, current := 0, 0, true
+	for rows.Next() {
+		var day int
+		if err = rows.Scan(&day); err != nil {
+			return err
+		}
+		if day > 0 {
+			continue
+		}
+
+		if streak > 0 && day == previous-1 {
+			streak++
+		} else {
+			// Only the first streak can be current, and only if it ends today
+			// or yesterday.
+			current = streak == 0 && day >= -1
+			streak = 1
+		}
+		if current {
+			stats.CurrentStreak = streak
+		}
+		if streak > stats.LongestStreak {
+			stats.LongestStreak = streak
+		}
+		previous = day
+	}
+	return rows.Err()
+}
@@ -0,0 +1,125 @@
+package model
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// ErrInvalidRefreshToken is returned for refresh tokens that are unknown,
+// expired, or already used.
+var ErrInvalidRefreshToken = errors.New("refresh token is invalid or expired")
+
+// TokenStore keeps the state that lets tokens be renewed and revoked.
+// Refresh tokens are identified by hashes so that the plain tokens are
+// never stored.
+type TokenStore interface {
+	// GetTokenVersion returns the version that the access tokens of a user
+	// must carry to be accepted.
+	GetTokenVersion(userId string) (int, error)
+
+	// GetUserRole returns the role that the access tokens of a user claim.
+	GetUserRole(userId string) (Role, error)
+
+	// CreateRefreshToken stores a refresh token of a user.
+	CreateRefreshToken(userId, hash string, expires time.Time) error
+
+	// UseRefreshToken revokes a refresh token and returns its user.
+	UseRefreshToken(hash string) (string, error)
+
+	// RevokeRefreshToken revokes one refresh token of a user.
+	RevokeRefreshToken(userId, hash string) error
+
+	// RevokeTokens revokes every access and refresh token of a user.
+	RevokeTokens(userId string) error
+}
+
+func (db *PsqlDB) GetTokenVersion(userId string) (int, error) {
+	var version int
+	err := db.QueryRow(`SELECT token_version FROM users WHERE id = $1`,
+		userId).Scan(&version)
+	return version, err
+}
+
+func (db *PsqlDB) CreateRefreshToken(userId, hash string,
+	expires time.Time) error {
+	_, err := db.Exec(`
+		INSERT INTO refresh_tokens (user_id, token_hash, expires_at)
+		VALUES ($1, $2, $3)`,
+		userId, hash, expires,
+	)
+	return err
+}
+
+// UseRefreshToken revokes a refresh token and returns its user. Tokens can
+// only be used once, so a token that is used again was likely stolen; all
+// tokens of its user are then revoked. Either way, ErrInvalidRefreshToken is
+// returned.
+func (db *PsqlDB) UseRefreshToken(hash string) (string, error) {
+	var userId string
+	err := db.QueryRow(`
+		UPDATE refresh_tokens SET revoked_at = NOW()
+		WHERE token_hash = $1
+		AND   revoked_at IS NULL
+		AND   expires_at > NOW()
+		RETURNING user_id`,
+		hash,
+	).Scan(&userId)
+	if err != sql.ErrNoRows {
+		return userId, err
+	}
+
+	var revoked bool
+	err = db.QueryRow(`
+		SELECT user_id, revoked_at IS NOT NULL FROM refresh_tokens
+		WHERE token_hash = $1`,
+		hash,
+	).Scan(&userId, &revoked)
+	if err == nil && revoked {
+		err = db.RevokeTokens(userId)
+	}
+	if err != nil && err != sql.ErrNoRows {
+		return "", err
+	}
+	return "", ErrInvalidRefreshToken
+}
+
+func (db *PsqlDB) RevokeRefreshToken(userId, hash string) error {
+	_, err := db.Exec(`
+		UPDATE refresh_tokens SET revoked_at = NOW()
+		WHERE user_id = $1 AND token_hash = $2 AND revoked_at IS NULL`,
+		userId, hash,
+	)
+	return err
+}
+
+func (db *PsqlDB) RevokeTokens(userId string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err = revokeTokens(tx, userId); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// revokeTokens revokes every token of a user as part of a transaction.
+func revokeTokens(tx *sql.Tx, userId string) error {
+	_, err := tx.Exec(`
+		UPDATE users SET token_version = token_version + 1
+		WHERE id = $1`,
+		userId,
+	)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`
+		UPDATE refresh_tokens SET revoked_at = NOW()
+		WHERE user_id = $1 AND revoked_at IS NULL`,
+		userId,
+	)
+	return err
+}
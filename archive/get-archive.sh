@@ -1,5 +1,5 @@
 #!/bin/bash
 # The docker-compose file requires a pages dump in this directory.
 # This script should be run before bringing up the containers.
+# anvil reads the compressed archive directly, so there is no need to unpack it.
 wget https://dumps.wikimedia.org/enwiktionary/latest/enwiktionary-latest-pages-meta-current.xml.bz2
-lbzip2 -d -k enwiktionary-latest-pages-meta-current.xml.bz2
@@ -11,10 +11,28 @@ CREATE TABLE languages (
     tag text UNIQUE -- Short code (e.g., en, es, nl)
 );
 
+-- Word search matches on trigrams and ignores accents (e.g., 'een' finds 'één').
+CREATE EXTENSION pg_trgm;
+CREATE EXTENSION unaccent;
+-- Suggestions for misspelled words are ranked by edit distance.
+CREATE EXTENSION fuzzystrmatch;
+
+-- unaccent is only stable, so it can't be used in an index. Its result does
+-- not change as long as the dictionary is fixed, which is what this wraps.
+CREATE FUNCTION f_unaccent(text) RETURNS text AS $$
+    SELECT public.unaccent('public.unaccent', $1)
+$$ LANGUAGE sql IMMUTABLE;
+
+-- A word is unique within its language. The same spelling in two languages
+-- (e.g., Dutch and English 'been') is two words.
 CREATE TABLE words (
     id serial PRIMARY KEY,
-    word text UNIQUE NOT NULL
+    lang_id int NOT NULL REFERENCES languages(id),
+    word text NOT NULL,
+    UNIQUE (lang_id, word)
 );
+-- Supports substring searches on words. Queries must use the same expression.
+CREATE INDEX words_search ON words USING gin (lower(f_unaccent(word)) gin_trgm_ops);
 
 -- Grammatical tense (e.g., present, past)
 CREATE TABLE tenses (
@@ -24,12 +42,54 @@ CREATE TABLE tenses (
 INSERT INTO tenses (tense)
 VALUES ('present'), ('past');
 
+-- Grammatical mood (e.g., indicative, subjunctive)
+CREATE TABLE moods (
+    id serial PRIMARY KEY,
+    mood text UNIQUE NOT NULL
+);
+INSERT INTO moods (mood)
+VALUES ('indicative'), ('subjunctive'), ('imperative');
+
 CREATE TABLE verb_forms (
     id serial PRIMARY KEY,
     lang_id  int NOT NULL REFERENCES languages(id),
     word_id  int NOT NULL REFERENCES words(id),
     inf_id   int NOT NULL REFERENCES words(id),
     tense_id int NOT NULL REFERENCES tenses(id),
+    mood_id  int NOT NULL REFERENCES moods(id),
     person   int, -- Plural verbs won't have a person.
-    num      int NOT NULL -- 1 is singular; not 1 is plural
+    num      int NOT NULL, -- 1 is singular; not 1 is plural
+    page_id  int -- The archive page it was imported from; null if added by hand
+);
+-- Updates replace everything that was imported from a changed page.
+CREATE INDEX verb_forms_page ON verb_forms (page_id);
+-- A verb form is stored once for each combination of these. Plural forms
+-- without a person would be distinct under a plain unique constraint, since
+-- nulls never equal each other. Note: anvil's dedupe command creates the same
+-- index on databases that predate it.
+CREATE UNIQUE INDEX verb_forms_natural_key ON verb_forms
+    (lang_id, word_id, inf_id, tense_id, mood_id, COALESCE(person, 0), num);
+
+-- The pattern a verb follows when conjugated (e.g., weak, strong)
+CREATE TABLE verb_classes (
+    id serial PRIMARY KEY,
+    class text UNIQUE NOT NULL
+);
+INSERT INTO verb_classes (class)
+VALUES ('weak'), ('strong'), ('mixed'), ('irregular');
+
+-- Properties shared by all forms of an infinitive
+CREATE TABLE infinitives (
+    lang_id     int NOT NULL REFERENCES languages(id),
+    word_id     int NOT NULL REFERENCES words(id),
+    class_id    int REFERENCES verb_classes(id),
+    subclass    text, -- A grouping within the class (e.g., ablaut series)
+    prefix      text, -- Separable prefix (e.g., 'an' in 'anfangen')
+    aux_id      int REFERENCES words(id), -- Perfect auxiliary (e.g., hebben)
+    pres_ptc_id int REFERENCES words(id), -- Present participle
+    past_ptc_id int REFERENCES words(id), -- Past participle
+    gerund_id   int REFERENCES words(id),
+    page_id     int, -- The archive page it was imported from
+    PRIMARY KEY (lang_id, word_id)
 );
+CREATE INDEX infinitives_page ON infinitives (page_id);
@@ -20,6 +20,9 @@ CREATE TABLE users (
     name text NOT NULL UNIQUE,
     target_language_id int REFERENCES languages(id),
     password text NOT NULL,
+    -- Access tokens carry the version they were issued under. Incrementing
+    -- it revokes every token that the user holds.
+    token_version int NOT NULL DEFAULT 0,
     created_at timestamp NOT NULL DEFAULT NOW()
 );
 
@@ -50,3 +53,15 @@ EXCEPTION
         RAISE EXCEPTION 'user % already exists', _name;
 END;
 $$ LANGUAGE plpgsql;
+
+-- Refresh tokens let clients get new access tokens without resending
+-- credentials. Each can be used once, and only a hash of it is stored.
+CREATE TABLE refresh_tokens (
+    id serial PRIMARY KEY,
+    user_id uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash text NOT NULL UNIQUE,
+    created_at timestamp NOT NULL DEFAULT NOW(),
+    expires_at timestamp NOT NULL,
+    revoked_at timestamp
+);
+CREATE INDEX refresh_tokens_user ON refresh_tokens (user_id);
@@ -0,0 +1,27 @@
+/* Application database schema
+ * RDBMS: PostgreSQL 9.5
+ *
+ * This file defines the schema that anvil uses to track imports. It does not
+ * rely on the other schemas.
+ */
+
+-- The progress of an import through an archive. If anvil stops early, it
+-- can resume after the page recorded here.
+CREATE TABLE import_checkpoints (
+    -- The base name of the archive file
+    archive text NOT NULL PRIMARY KEY,
+    page_id int NOT NULL,
+    page_title text NOT NULL,
+    -- The position in the decompressed archive that follows the page
+    byte_offset bigint NOT NULL,
+    updated_at timestamp NOT NULL DEFAULT NOW()
+);
+
+-- The revision of each archive page that was last imported. Updates skip
+-- pages whose revision has already been seen.
+CREATE TABLE pages (
+    id int NOT NULL PRIMARY KEY, -- The page id used by the archive
+    title text NOT NULL,
+    revision_id int NOT NULL,
+    revised_at timestamp NOT NULL
+);
@@ -0,0 +1,76 @@
+/* Application database schema
+ * RDBMS: PostgreSQL 9.5
+ *
+ * This file defines the schema for learner practice. It relies on the core
+ * and user schemas.
+ */
+
+-- A prompt to give one form of a verb (e.g., 'krijgen, 3rd person singular
+-- past'). The form isn't stored; answers are checked against verb_forms,
+-- which may hold several correct spellings.
+CREATE TABLE drills (
+    id serial PRIMARY KEY,
+    user_id  uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    lang_id  int NOT NULL REFERENCES languages(id),
+    inf_id   int NOT NULL REFERENCES words(id),
+    tense_id int NOT NULL REFERENCES tenses(id),
+    mood_id  int NOT NULL REFERENCES moods(id),
+    person   int, -- A single person, or null for plural prompts
+    num      int NOT NULL,
+    created_at timestamp NOT NULL DEFAULT NOW(),
+    -- These are null until the drill is answered.
+    answer      text,
+    correct     boolean,
+    answered_at timestamp
+);
+CREATE INDEX drills_user ON drills (user_id, created_at);
+
+-- The spaced repetition state of each cell that a user studies. The columns
+-- that identify the cell match those of drills.
+CREATE TABLE review_cards (
+    id serial PRIMARY KEY,
+    user_id  uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    lang_id  int NOT NULL REFERENCES languages(id),
+    inf_id   int NOT NULL REFERENCES words(id),
+    tense_id int NOT NULL REFERENCES tenses(id),
+    mood_id  int NOT NULL REFERENCES moods(id),
+    person   int,
+    num      int NOT NULL,
+    -- SM-2 state; see api/model/scheduler
+    repetitions   int NOT NULL DEFAULT 0,
+    interval_days int NOT NULL DEFAULT 0,
+    ease          double precision NOT NULL DEFAULT 2.5,
+    lapses        int NOT NULL DEFAULT 0,
+    due_at      timestamp NOT NULL DEFAULT NOW(),
+    reviewed_at timestamp,
+    created_at  timestamp NOT NULL DEFAULT NOW()
+);
+-- A user has one card per cell.
+CREATE UNIQUE INDEX review_cards_cell ON review_cards
+    (user_id, inf_id, tense_id, mood_id, COALESCE(person, 0), num);
+CREATE INDEX review_cards_due ON review_cards (user_id, due_at);
+
+-- Every grade given to a card
+CREATE TABLE reviews (
+    id serial PRIMARY KEY,
+    card_id int NOT NULL REFERENCES review_cards(id) ON DELETE CASCADE,
+    grade int NOT NULL CHECK (grade BETWEEN 0 AND 5),
+    reviewed_at timestamp NOT NULL DEFAULT NOW()
+);
+CREATE INDEX reviews_card ON reviews (card_id, reviewed_at);
+
+-- Whether a user gave the right form of a cell. Drills and reviews record
+-- their outcomes here, and clients can add results of their own exercises.
+CREATE TABLE practice_results (
+    id serial PRIMARY KEY,
+    user_id  uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    lang_id  int NOT NULL REFERENCES languages(id),
+    inf_id   int NOT NULL REFERENCES words(id),
+    tense_id int NOT NULL REFERENCES tenses(id),
+    mood_id  int NOT NULL REFERENCES moods(id),
+    person   int,
+    num      int NOT NULL,
+    correct  boolean NOT NULL,
+    practiced_at timestamp NOT NULL DEFAULT NOW()
+);
+CREATE INDEX practice_results_user ON practice_results (user_id, practiced_at);
@@ -0,0 +1,61 @@
+/* Application database schema
+ * RDBMS: PostgreSQL 9.5
+ *
+ * This file defines the history of edits that admins make to conjugation
+ * data and the corrections that users propose. It relies on the core and
+ * user schemas.
+ */
+
+-- Each row is one change to a word or verb form. Changes to verb forms are
+-- filed under their infinitive so that the history of a verb includes them.
+-- word_id is not a foreign key because history outlives deleted words.
+CREATE TABLE audit_log (
+    id serial PRIMARY KEY,
+    editor_id  uuid REFERENCES users(id) ON DELETE SET NULL,
+    word_id    int NOT NULL,
+    table_name text NOT NULL,
+    row_id     int NOT NULL,
+    action     text NOT NULL CHECK (action IN ('create', 'update', 'delete')),
+    before     jsonb, -- NULL for creations
+    after      jsonb, -- NULL for deletions
+    changed_at timestamp NOT NULL DEFAULT NOW()
+);
+CREATE INDEX audit_log_word ON audit_log (word_id, changed_at);
+
+-- Corrections are verb forms that users propose and admins review. A
+-- correction either replaces the form replaces_id or, if that is NULL, adds
+-- a new one. replaces_id is not a foreign key so that a correction survives
+-- the form it was made against. Once accepted, form holds the verb form that
+-- was applied.
+CREATE TABLE corrections (
+    id serial PRIMARY KEY,
+    submitter_id uuid REFERENCES users(id) ON DELETE SET NULL,
+    lang_id      int NOT NULL REFERENCES languages(id),
+    replaces_id  int,
+    form         jsonb NOT NULL,
+    comment      text NOT NULL DEFAULT '',
+    status       text NOT NULL DEFAULT 'pending'
+                 CHECK (status IN ('pending', 'accepted', 'rejected')),
+    reviewer_id  uuid REFERENCES users(id) ON DELETE SET NULL,
+    review_note  text NOT NULL DEFAULT '',
+    created_at   timestamp NOT NULL DEFAULT NOW(),
+    reviewed_at  timestamp
+);
+CREATE INDEX corrections_status ON corrections (status, lang_id);
+
+-- Tombstones of verb forms that admins deleted or replaced, by the natural
+-- key of verb_forms. Imports and updates skip forms that have one, so edits
+-- aren't undone when a page is imported again. Keeping deleted rows in
+-- verb_forms instead would mean filtering them out of every query. Creating
+-- the same form by hand removes its tombstone.
+CREATE TABLE deleted_verb_forms (
+    lang_id  int NOT NULL REFERENCES languages(id),
+    word_id  int NOT NULL REFERENCES words(id) ON DELETE CASCADE,
+    inf_id   int NOT NULL REFERENCES words(id) ON DELETE CASCADE,
+    tense_id int NOT NULL REFERENCES tenses(id),
+    mood_id  int NOT NULL REFERENCES moods(id),
+    person   int NOT NULL, -- 0 for plural forms without a person
+    num      int NOT NULL,
+    deleted_at timestamp NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (lang_id, word_id, inf_id, tense_id, mood_id, person, num)
+);
@@ -36,7 +36,10 @@ class App extends Component {
         return (
             <div className="center-wrap">
                 <div className="app">
-                    <SearchBar onSearchWord={verb => this.findAndStore(verb)}/>
+                    <SearchBar
+                        onSearchWord={verb => this.findAndStore(verb)}
+                        onSuggestWords={(text, callback) =>
+                            this.state.search.searchWords(text, callback)}/>
                     <InflectionTable inf={this.state.inflection} />
                 </div>
             </div>
@@ -19,23 +19,23 @@ class InflectionTable extends Component {
                 </tr>
                 <tr>
                     <th>1st person singular</th>
-                    <th>{this.props.inf.Present.First.join(', ')}</th>
-                    <th>{this.props.inf.Past.First.join(', ')}</th>
+                    <th>{this.props.inf.Indicative.Present.First.join(', ')}</th>
+                    <th>{this.props.inf.Indicative.Past.First.join(', ')}</th>
                 </tr>
                 <tr>
                     <th>2nd person singular</th>
-                    <th>{this.props.inf.Present.Second.join(', ')}</th>
-                    <th>{this.props.inf.Past.Second.join(', ')}</th>
+                    <th>{this.props.inf.Indicative.Present.Second.join(', ')}</th>
+                    <th>{this.props.inf.Indicative.Past.Second.join(', ')}</th>
                 </tr>
                 <tr>
                     <th>3rd person singular</th>
-                    <th>{this.props.inf.Present.Third.join(', ')}</th>
-                    <th>{this.props.inf.Past.Third.join(', ')}</th>
+                    <th>{this.props.inf.Indicative.Present.Third.join(', ')}</th>
+                    <th>{this.props.inf.Indicative.Past.Third.join(', ')}</th>
                 </tr>
                 <tr>
                     <th>Plural</th>
-                    <th>{this.props.inf.Present.Plural.join(', ')}</th>
-                    <th>{this.props.inf.Past.Plural.join(', ')}</th>
+                    <th>{this.props.inf.Indicative.Present.Plural.join(', ')}</th>
+                    <th>{this.props.inf.Indicative.Past.Plural.join(', ')}</th>
                 </tr>
             </tbody></table>
         );
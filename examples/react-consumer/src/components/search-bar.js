@@ -1,17 +1,30 @@
 import React, { Component } from 'react';
 import '../styles/search-bar.css';
 
-/** An input form used for verb lookup */
+/**
+ * An input form used for verb lookup
+ *
+ * Words suggested by the onSuggestWords prop are offered as the user types.
+ */
 class SearchBar extends Component {
     constructor(props) {
         super(props);
-        this.state = { word: 'fiets' }
+        this.state = { word: 'fiets', suggestions: [] }
     }
 
     render() {
-        return <input
-            value={this.state.word}
-            onChange={event => this.onSearchChange(event.target.value)} />;
+        return (
+            <div>
+                <input
+                    list="word-suggestions"
+                    value={this.state.word}
+                    onChange={event => this.onSearchChange(event.target.value)} />
+                <datalist id="word-suggestions">
+                    {this.state.suggestions.map(word =>
+                        <option key={word} value={word} />)}
+                </datalist>
+            </div>
+        );
     }
 
     /**
@@ -22,6 +35,9 @@ class SearchBar extends Component {
         this.setState({ word });
         if (word.length === 0) return;        
         this.props.onSearchWord(word);
+        this.props.onSuggestWords(word, suggestions => {
+            this.setState({ suggestions })
+        });
     }
 }
 
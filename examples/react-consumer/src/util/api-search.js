@@ -1,7 +1,14 @@
 /** Facilitates word searches using the Mutably API */
 class ApiSearch {
-    constructor() {
+    /**
+     * @param {string} language the name of the language to search
+     */
+    constructor(language = 'dutch') {
         this.apiV1 = 'http://srv.marcusposey.com:9000/api/v1';
+        // Words are looked up within a language, so find its id once.
+        this.languageId = fetch(this.apiV1 + '/languages')
+            .then(response => response.json())
+            .then(languages => languages.find(lang => lang.name === language).id);
     }
 
     /**
@@ -12,23 +19,45 @@ class ApiSearch {
      *                            as an argument
      */
     findVerb(verb, callback) {
-        const resource = this.apiV1 + '/words/' + verb + '/inflections';
-        fetch(resource)
+        this.languageId
+            .then(id => fetch(this.apiV1 + '/languages/' + id + '/words/' +
+                encodeURIComponent(verb) + '/inflections'))
             .then(response => {
                 if (response.status === 404) {
                     return ApiSearch.getConjugationTable();
                 }
                 return response.json();
             })
+            .catch(() => ApiSearch.getConjugationTable())
             .then(callback);
     }
 
+    /**
+     * Retrieves words that contain text, best matches first
+     * An empty array is passed to callback if there are none.
+     * @param {string} text the partial word to search for
+     * @param {Function} callback a function which takes an array of strings
+     */
+    searchWords(text, callback) {
+        this.languageId
+            .then(id => fetch(this.apiV1 + '/words?limit=10&language=' + id +
+                '&q=' + encodeURIComponent(text)))
+            .then(response => response.status === 200 ? response.json() : [])
+            .catch(() => [])
+            .then(words => callback(words.map(word => word.text)));
+    }
+
     /** Retrieves a blank conjugation table */
     static getConjugationTable() {
-        return {
-            Infinitive: '',
+        const mood = () => ({
             Present: {First: [], Second: [], Third: [], Plural: []},
             Past: {First: [], Second: [], Third: [], Plural: []}
+        });
+        return {
+            Infinitive: '',
+            Indicative: mood(),
+            Subjunctive: mood(),
+            Imperative: mood()
         }
     }
 }